@@ -0,0 +1,141 @@
+package main
+
+import (
+	"bytes"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/webdevops/github-workflow-exporter/config"
+)
+
+var (
+	AppConfig = config.NewConfig()
+
+	// reloaded config, applied by the collector on the next collection run
+	appConfigPending atomic.Pointer[config.Config]
+)
+
+func initConfig() {
+	if Opts.Config.Path == "" && Opts.Config.LabelMapping == "" {
+		return
+	}
+
+	appConfig, err := loadConfig()
+	if err != nil {
+		logger.Fatal(`unable to load config`, slog.Any("error", err))
+	}
+	AppConfig = appConfig
+}
+
+// loadConfig loads the config file and the label mapping file (if set)
+func loadConfig() (*config.Config, error) {
+	appConfig := config.NewConfig()
+
+	if Opts.Config.Path != "" {
+		logger.Info(`loading config file`, slog.String("path", Opts.Config.Path))
+
+		var err error
+		appConfig, err = config.LoadConfig(Opts.Config.Path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if Opts.Config.LabelMapping != "" {
+		logger.Info(`loading label mapping file`, slog.String("path", Opts.Config.LabelMapping))
+
+		labelMapping, err := config.LoadLabelMapping(Opts.Config.LabelMapping)
+		if err != nil {
+			return nil, err
+		}
+		appConfig.LabelMapping = labelMapping
+	}
+
+	return appConfig, nil
+}
+
+// reloadConfig loads the config files and schedules them for the next collection run (previous config is kept on errors)
+func reloadConfig() {
+	appConfig, err := loadConfig()
+	if err != nil {
+		logger.Error(`unable to reload config, keeping previous config`, slog.Any("error", err))
+		return
+	}
+
+	// collection runs can't be triggered, reloaded config is applied up to scrape time later
+	appConfigPending.Store(appConfig)
+	logger.Info(`config reloaded, applying on start of next collection run (up to scrape time later)`, slog.Duration("scrapeTime", Opts.Scrape.Time))
+}
+
+// takePendingConfig returns the reloaded config if there is one (only once)
+func takePendingConfig() *config.Config {
+	return appConfigPending.Swap(nil)
+}
+
+// startConfigWatcher reloads the config files on SIGHUP or when the content of a file changes
+func startConfigWatcher() {
+	var paths []string
+	for _, path := range []string{Opts.Config.Path, Opts.Config.LabelMapping} {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+
+	if len(paths) == 0 {
+		return
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	var ticker <-chan time.Time
+	if Opts.Config.ReloadInterval > 0 {
+		ticker = time.NewTicker(Opts.Config.ReloadInterval).C
+	}
+
+	logger.Info(`watching config files for changes`, slog.Any("paths", paths), slog.Duration("interval", Opts.Config.ReloadInterval))
+
+	readContents := func() map[string][]byte {
+		contents := map[string][]byte{}
+		for _, path := range paths {
+			contents[path], _ = os.ReadFile(path) // #nosec G304 path is configured by user
+		}
+		return contents
+	}
+
+	go func() {
+		contents := readContents()
+
+		for {
+			select {
+			case <-sighup:
+				logger.Info(`received SIGHUP`)
+			case <-ticker:
+				changed := false
+				for _, path := range paths {
+					currentContent, err := os.ReadFile(path) // #nosec G304 path is configured by user
+					if err != nil {
+						logger.Warn(`unable to read config file`, slog.String("path", path), slog.Any("error", err))
+						continue
+					}
+
+					if !bytes.Equal(contents[path], currentContent) {
+						logger.Info(`detected config file change`, slog.String("path", path))
+						changed = true
+					}
+				}
+
+				if !changed {
+					continue
+				}
+			}
+
+			contents = readContents()
+			reloadConfig()
+		}
+	}()
+}
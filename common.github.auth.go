@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	ghinstallation "github.com/bradleyfalzon/ghinstallation/v2"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+type (
+	// GithubTokenTransport adds a (reloadable) GitHub token to every request
+	GithubTokenTransport struct {
+		transport http.RoundTripper
+
+		lock  sync.RWMutex
+		token string
+	}
+
+	// GithubAppSigner signs GitHub app JWTs using a (reloadable) private key
+	GithubAppSigner struct {
+		lock   sync.RWMutex
+		signer *ghinstallation.RSASigner
+	}
+
+	// githubCredentialFile is a credential file which is watched for changes
+	githubCredentialFile struct {
+		path     string
+		content  []byte
+		callback func(content []byte) error
+	}
+)
+
+var (
+	githubCredentialFiles []*githubCredentialFile
+)
+
+// NewGithubTokenTransport creates a new transport with token auth
+func NewGithubTokenTransport(transport http.RoundTripper, token string) *GithubTokenTransport {
+	t := &GithubTokenTransport{transport: transport}
+	t.SetToken(token)
+	return t
+}
+
+// SetToken replaces the token used for further requests
+func (t *GithubTokenTransport) SetToken(token string) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	t.token = strings.TrimSpace(token)
+}
+
+// RoundTrip implements http.RoundTripper interface
+func (t *GithubTokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.lock.RLock()
+	token := t.token
+	t.lock.RUnlock()
+
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return t.transport.RoundTrip(req)
+}
+
+// NewGithubAppSigner creates a new GitHub app JWT signer using the private key (PEM or base64 encoded PEM)
+func NewGithubAppSigner(privateKey []byte) (*GithubAppSigner, error) {
+	s := &GithubAppSigner{}
+	if err := s.SetPrivateKey(privateKey); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// SetPrivateKey replaces the private key (PEM or base64 encoded PEM) used for further signing
+func (s *GithubAppSigner) SetPrivateKey(privateKey []byte) error {
+	privateKey = bytes.TrimSpace(privateKey)
+
+	// support base64 encoded private keys (eg. from env vars)
+	if !bytes.HasPrefix(privateKey, []byte("-----BEGIN")) {
+		decodedKey, err := base64.StdEncoding.DecodeString(string(privateKey))
+		if err != nil {
+			return errors.New(`private key is neither PEM nor base64 encoded PEM`)
+		}
+		privateKey = decodedKey
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(privateKey)
+	if err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.signer = ghinstallation.NewRSASigner(jwt.SigningMethodRS256, key)
+
+	return nil
+}
+
+// Sign implements ghinstallation.Signer interface
+func (s *GithubAppSigner) Sign(claims jwt.Claims) (string, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.signer.Sign(claims)
+}
+
+// readGithubCredentialFile reads a credential file and registers it for reload on changes
+func readGithubCredentialFile(path string, callback func(content []byte) error) ([]byte, error) {
+	content, err := os.ReadFile(path) // #nosec G304 path is configured by user
+	if err != nil {
+		return nil, err
+	}
+
+	githubCredentialFiles = append(githubCredentialFiles, &githubCredentialFile{
+		path:     path,
+		content:  content,
+		callback: callback,
+	})
+
+	return content, nil
+}
+
+// startGithubCredentialWatcher checks all credential files for changes and reloads them
+func startGithubCredentialWatcher(interval time.Duration) {
+	if len(githubCredentialFiles) == 0 || interval <= 0 {
+		return
+	}
+
+	logger.Info(`watching GitHub credential files for changes`, slog.Duration("interval", interval))
+
+	go func() {
+		for {
+			time.Sleep(interval)
+
+			for _, credentialFile := range githubCredentialFiles {
+				credentialLogger := logger.With(slog.String("path", credentialFile.path))
+
+				content, err := os.ReadFile(credentialFile.path)
+				if err != nil {
+					credentialLogger.Warn(`unable to read GitHub credential file`, slog.Any("error", err))
+					continue
+				}
+
+				if bytes.Equal(content, credentialFile.content) {
+					continue
+				}
+
+				if err := credentialFile.callback(content); err != nil {
+					credentialLogger.Error(`unable to reload GitHub credential file, keeping previous credentials`, slog.Any("error", err))
+					continue
+				}
+
+				credentialFile.content = content
+				credentialLogger.Info(`reloaded GitHub credential file`)
+			}
+		}
+	}()
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	ghinstallation "github.com/bradleyfalzon/ghinstallation/v2"
+	github "github.com/google/go-github/v61/github"
+)
+
+type (
+	GithubOrganization struct {
+		Name           string
+		InstallationID *int64
+
+		// client using all credentials of the organization (pooled)
+		Client *github.Client
+
+		// client using only the app installation credential (for installation scoped requests)
+		InstallationClient *github.Client
+	}
+)
+
+var (
+	githubOrganizations     []*GithubOrganization
+	githubOrganizationsLock sync.Mutex
+
+	// base transport for all requests
+	githubHttpTransport *http.Transport
+
+	// token auth
+	githubTokenTransports = map[string]http.RoundTripper{}
+
+	// app auth
+	githubAppSigner *GithubAppSigner
+
+	// app auth without installation id, installations are discovered
+	githubAppsTransport *ghinstallation.AppsTransport
+	githubAppsClient    *github.Client
+)
+
+// newGithubHttpTransport creates the base http transport (proxy and TLS settings) used for all GitHub requests
+func newGithubHttpTransport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if Opts.GitHub.HTTP.Proxy != "" {
+		proxyURL, err := url.Parse(Opts.GitHub.HTTP.Proxy)
+		if err != nil {
+			logger.Fatal(`unable to parse GitHub proxy url`, slog.Any("error", err))
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+
+	if Opts.GitHub.HTTP.TLS.CaFile != nil {
+		rootCAs, err := x509.SystemCertPool()
+		if err != nil {
+			logger.Warn(`unable to load system CA pool, only using GitHub CA bundle`, slog.Any("error", err))
+			rootCAs = x509.NewCertPool()
+		}
+
+		caBundle, err := os.ReadFile(*Opts.GitHub.HTTP.TLS.CaFile)
+		if err != nil {
+			logger.Fatal(`unable to read GitHub CA bundle`, slog.Any("error", err))
+		}
+
+		if !rootCAs.AppendCertsFromPEM(caBundle) {
+			logger.Fatal(`unable to parse GitHub CA bundle, no certificates found`)
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if Opts.GitHub.HTTP.TLS.CertFile != nil || Opts.GitHub.HTTP.TLS.KeyFile != nil {
+		if Opts.GitHub.HTTP.TLS.CertFile == nil || Opts.GitHub.HTTP.TLS.KeyFile == nil {
+			logger.Fatal(`GitHub TLS client certificate requires both certificate and key`)
+		}
+
+		clientCert, err := tls.LoadX509KeyPair(*Opts.GitHub.HTTP.TLS.CertFile, *Opts.GitHub.HTTP.TLS.KeyFile)
+		if err != nil {
+			logger.Fatal(`unable to load GitHub TLS client certificate`, slog.Any("error", err))
+		}
+		tlsConfig.Certificates = []tls.Certificate{clientCert}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+
+	return transport
+}
+
+// newGithubHttpClient creates a http client with the configured request timeout
+func newGithubHttpClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Transport: transport,
+		Timeout:   Opts.GitHub.HTTP.Timeout,
+	}
+}
+
+// newGithubClient creates a new GitHub client using transport with user agent and enterprise urls
+func newGithubClient(transport http.RoundTripper) *github.Client {
+	client := github.NewClient(newGithubHttpClient(transport))
+	client.UserAgent = fmt.Sprintf(`%s/%s`, UserAgent, gitTag)
+
+	if Opts.GitHub.EnterpriseURL != "" {
+		var err error
+		client, err = client.WithEnterpriseURLs(Opts.GitHub.EnterpriseURL, "")
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+	}
+
+	return client
+}
+
+// githubEnterpriseApiURL returns the api url of the GitHub enterprise server (with trailing slash)
+func githubEnterpriseApiURL() string {
+	apiURL := Opts.GitHub.EnterpriseURL
+
+	if !strings.HasSuffix(apiURL, "/") {
+		apiURL += "/"
+	}
+	if !strings.HasSuffix(apiURL, "/api/v3/") {
+		apiURL += "api/v3/"
+	}
+
+	return apiURL
+}
+
+// newGithubInstallationTransport creates a transport for an app installation (token refresh requests are using the request timeout)
+func newGithubInstallationTransport(atr *ghinstallation.AppsTransport, installationID int64) *ghinstallation.Transport {
+	itr := ghinstallation.NewFromAppsTransport(atr, installationID)
+	itr.Client = newGithubHttpClient(githubHttpTransport)
+	return itr
+}
+
+// newGithubOrganization creates a new organization, tokens are pooled with the app installation if the organization matches
+func newGithubOrganization(name string, installationTransport *ghinstallation.Transport) *GithubOrganization {
+	org := &GithubOrganization{Name: name}
+
+	transports := map[string]http.RoundTripper{}
+	if installationTransport != nil {
+		installationID := installationTransport.InstallationID()
+		org.InstallationID = &installationID
+		org.InstallationClient = newGithubClient(installationTransport)
+		transports[fmt.Sprintf("installation#%d", installationID)] = installationTransport
+	}
+
+	if strings.EqualFold(name, Opts.GitHub.Organization) {
+		for credentialName, transport := range githubTokenTransports {
+			transports[credentialName] = transport
+		}
+	}
+
+	org.Client = newGithubClient(newGithubTransport(transports))
+
+	return org
+}
+
+// getGithubOrganizations returns list of GitHub organizations which should be collected
+func getGithubOrganizations() []*GithubOrganization {
+	githubOrganizationsLock.Lock()
+	defer githubOrganizationsLock.Unlock()
+
+	return githubOrganizations
+}
+
+// discoverGithubAppInstallations lists all installations of the GitHub app and updates the list of organizations
+func discoverGithubAppInstallations(ctx context.Context) error {
+	var installations []*github.Installation
+
+	opts := github.ListOptions{PerPage: 100, Page: 1}
+
+	for {
+		logger.Debug(`fetching GitHub app installation list`, slog.Int("page", opts.Page))
+
+		result, response, err := githubAppsClient.Apps.ListInstallations(ctx, &opts)
+		var ghRateLimitError *github.RateLimitError
+		if ok := errors.As(err, &ghRateLimitError); ok {
+			logger.Debug("request ListInstallations rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+			time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		installations = append(installations, result...)
+
+		// calc next page
+		if response.NextPage == 0 {
+			break
+		}
+		opts.Page = response.NextPage
+	}
+
+	githubOrganizationsLock.Lock()
+	defer githubOrganizationsLock.Unlock()
+
+	// reuse existing clients to keep their cached installation tokens
+	existingOrganizations := map[int64]*GithubOrganization{}
+	for _, org := range githubOrganizations {
+		if org.InstallationID != nil {
+			existingOrganizations[*org.InstallationID] = org
+		}
+	}
+
+	var organizations []*GithubOrganization
+	for _, installation := range installations {
+		installationID := installation.GetID()
+		orgName := installation.GetAccount().GetLogin()
+		installationLogger := logger.With(slog.Int64("installationID", installationID), slog.String("org", orgName))
+
+		if !strings.EqualFold(installation.GetTargetType(), "Organization") {
+			installationLogger.Debug(`ignoring GitHub app installation, not installed on an organization`, slog.String("targetType", installation.GetTargetType()))
+			continue
+		}
+
+		if installation.SuspendedAt != nil {
+			installationLogger.Debug(`ignoring GitHub app installation, installation is suspended`)
+			continue
+		}
+
+		if Opts.GitHub.Organization != "" && !strings.EqualFold(Opts.GitHub.Organization, orgName) {
+			installationLogger.Debug(`ignoring GitHub app installation, organization not matching`)
+			continue
+		}
+
+		if org, exists := existingOrganizations[installationID]; exists {
+			organizations = append(organizations, org)
+			continue
+		}
+
+		installationLogger.Info(`found new GitHub app installation`)
+		organizations = append(organizations, newGithubOrganization(orgName, newGithubInstallationTransport(githubAppsTransport, installationID)))
+	}
+
+	githubOrganizations = organizations
+
+	return nil
+}
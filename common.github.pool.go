@@ -0,0 +1,190 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	githubHeaderRateLimit     = "X-RateLimit-Limit"
+	githubHeaderRateRemaining = "X-RateLimit-Remaining"
+	githubHeaderRateReset     = "X-RateLimit-Reset"
+)
+
+type (
+	// GithubCredentialPool spreads requests across multiple credentials,
+	// picking the credential with the most remaining rate limit budget
+	GithubCredentialPool struct {
+		lock        sync.Mutex
+		credentials []*githubPoolCredential
+		next        int
+	}
+
+	githubPoolCredential struct {
+		name      string
+		transport http.RoundTripper
+
+		// rate limit state, unknown until first response
+		known     bool
+		limit     int
+		remaining int
+		reset     time.Time
+	}
+)
+
+// NewGithubCredentialPool creates a new credential pool, every transport is one credential
+func NewGithubCredentialPool(transports map[string]http.RoundTripper) *GithubCredentialPool {
+	pool := &GithubCredentialPool{}
+	for name, transport := range transports {
+		pool.credentials = append(pool.credentials, &githubPoolCredential{
+			name:      name,
+			transport: transport,
+		})
+	}
+	return pool
+}
+
+// newGithubTransport returns the transport itself for one credential or a credential pool for multiple credentials
+func newGithubTransport(transports map[string]http.RoundTripper) http.RoundTripper {
+	if len(transports) == 1 {
+		for _, transport := range transports {
+			return transport
+		}
+	}
+
+	return NewGithubCredentialPool(transports)
+}
+
+// budget returns the remaining rate limit budget of the credential
+func (c *githubPoolCredential) budget(now time.Time) int {
+	if !c.known || now.After(c.reset) {
+		// unknown or reset already happened, assume full budget
+		return int(^uint(0) >> 1)
+	}
+	return c.remaining
+}
+
+// pick selects the credential with the most remaining budget (round-robin for equal budgets)
+func (p *GithubCredentialPool) pick(exclude map[*githubPoolCredential]bool) *githubPoolCredential {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+
+	var selected *githubPoolCredential
+	for i := range p.credentials {
+		credential := p.credentials[(p.next+i)%len(p.credentials)]
+		if exclude[credential] {
+			continue
+		}
+
+		if selected == nil || credential.budget(now) > selected.budget(now) {
+			selected = credential
+		}
+	}
+
+	p.next = (p.next + 1) % len(p.credentials)
+
+	return selected
+}
+
+// update stores rate limit state from response headers
+func (p *GithubCredentialPool) update(credential *githubPoolCredential, response *http.Response) {
+	limit, errLimit := strconv.Atoi(response.Header.Get(githubHeaderRateLimit))
+	remaining, errRemaining := strconv.Atoi(response.Header.Get(githubHeaderRateRemaining))
+	reset, errReset := strconv.ParseInt(response.Header.Get(githubHeaderRateReset), 10, 64)
+	if errLimit != nil || errRemaining != nil || errReset != nil {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	credential.known = true
+	credential.limit = limit
+	credential.remaining = remaining
+	credential.reset = time.Unix(reset, 0)
+}
+
+// aggregate rewrites rate limit headers with the summarized state of the pool,
+// so the GitHub client only stops sending requests when all credentials are exhausted
+func (p *GithubCredentialPool) aggregate(response *http.Response) {
+	if response.Header.Get(githubHeaderRateRemaining) == "" {
+		return
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := time.Now()
+
+	limit := 0
+	remaining := 0
+	var reset time.Time
+	for _, credential := range p.credentials {
+		if !credential.known || now.After(credential.reset) {
+			// credential is not exhausted, unknown budget
+			return
+		}
+
+		limit += credential.limit
+		remaining += credential.remaining
+		if reset.IsZero() || credential.reset.Before(reset) {
+			reset = credential.reset
+		}
+	}
+
+	response.Header.Set(githubHeaderRateLimit, strconv.Itoa(limit))
+	response.Header.Set(githubHeaderRateRemaining, strconv.Itoa(remaining))
+	response.Header.Set(githubHeaderRateReset, strconv.FormatInt(reset.Unix(), 10))
+}
+
+// isRateLimited checks if response was rejected because of exhausted rate limit
+func (p *GithubCredentialPool) isRateLimited(response *http.Response) bool {
+	if response.StatusCode != http.StatusForbidden && response.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	return response.Header.Get(githubHeaderRateRemaining) == "0"
+}
+
+// RoundTrip implements http.RoundTripper interface
+func (p *GithubCredentialPool) RoundTrip(req *http.Request) (*http.Response, error) {
+	tried := map[*githubPoolCredential]bool{}
+
+	for {
+		credential := p.pick(tried)
+		tried[credential] = true
+
+		response, err := credential.transport.RoundTrip(req)
+		if err != nil {
+			return response, err
+		}
+
+		p.update(credential, response)
+
+		// try next credential if this one is exhausted (only possible if request can be replayed)
+		if p.isRateLimited(response) && len(tried) < len(p.credentials) && (req.Body == nil || req.GetBody != nil) {
+			logger.Debug(`GitHub credential rate limited, trying next credential`, slog.String("credential", credential.name))
+
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return response, nil
+				}
+				req = req.Clone(req.Context())
+				req.Body = body
+			}
+
+			// #nosec G104 body is not used anymore
+			response.Body.Close() // nolint:errcheck
+			continue
+		}
+
+		p.aggregate(response)
+
+		return response, nil
+	}
+}
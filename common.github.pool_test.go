@@ -0,0 +1,216 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGithubCredentialPoolPick(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name        string
+		credentials []*githubPoolCredential
+		exclude     []int
+		expected    string
+	}{
+		{
+			name: "most remaining budget",
+			credentials: []*githubPoolCredential{
+				{name: "a", known: true, remaining: 100, reset: now.Add(time.Hour)},
+				{name: "b", known: true, remaining: 4000, reset: now.Add(time.Hour)},
+				{name: "c", known: true, remaining: 10, reset: now.Add(time.Hour)},
+			},
+			expected: "b",
+		},
+		{
+			name: "unknown budget is preferred",
+			credentials: []*githubPoolCredential{
+				{name: "a", known: true, remaining: 4000, reset: now.Add(time.Hour)},
+				{name: "b"},
+			},
+			expected: "b",
+		},
+		{
+			name: "budget after reset is full",
+			credentials: []*githubPoolCredential{
+				{name: "a", known: true, remaining: 4000, reset: now.Add(time.Hour)},
+				{name: "b", known: true, remaining: 0, reset: now.Add(-time.Minute)},
+			},
+			expected: "b",
+		},
+		{
+			name: "excluded credentials are skipped",
+			credentials: []*githubPoolCredential{
+				{name: "a", known: true, remaining: 4000, reset: now.Add(time.Hour)},
+				{name: "b", known: true, remaining: 10, reset: now.Add(time.Hour)},
+			},
+			exclude:  []int{0},
+			expected: "b",
+		},
+		{
+			name: "all credentials excluded",
+			credentials: []*githubPoolCredential{
+				{name: "a", known: true, remaining: 4000, reset: now.Add(time.Hour)},
+			},
+			exclude:  []int{0},
+			expected: "",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pool := &GithubCredentialPool{credentials: testCase.credentials}
+
+			exclude := map[*githubPoolCredential]bool{}
+			for _, num := range testCase.exclude {
+				exclude[testCase.credentials[num]] = true
+			}
+
+			selected := pool.pick(exclude)
+			switch {
+			case testCase.expected == "" && selected != nil:
+				t.Errorf("expected no credential, got %v", selected.name)
+			case testCase.expected != "" && selected == nil:
+				t.Errorf("expected credential %v, got none", testCase.expected)
+			case selected != nil && selected.name != testCase.expected:
+				t.Errorf("expected credential %v, got %v", testCase.expected, selected.name)
+			}
+		})
+	}
+}
+
+func TestGithubCredentialPoolPickRoundRobin(t *testing.T) {
+	pool := &GithubCredentialPool{
+		credentials: []*githubPoolCredential{{name: "a"}, {name: "b"}, {name: "c"}},
+	}
+
+	var picked []string
+	for i := 0; i < 4; i++ {
+		picked = append(picked, pool.pick(nil).name)
+	}
+
+	expected := []string{"a", "b", "c", "a"}
+	for num := range expected {
+		if picked[num] != expected[num] {
+			t.Fatalf("expected round-robin %v for equal budgets, got %v", expected, picked)
+		}
+	}
+}
+
+func TestGithubCredentialPoolUpdate(t *testing.T) {
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	testCases := []struct {
+		name     string
+		headers  map[string]string
+		expected bool
+	}{
+		{
+			name: "rate limit headers",
+			headers: map[string]string{
+				githubHeaderRateLimit:     "5000",
+				githubHeaderRateRemaining: "4000",
+				githubHeaderRateReset:     strconv.FormatInt(reset.Unix(), 10),
+			},
+			expected: true,
+		},
+		{
+			name: "incomplete rate limit headers",
+			headers: map[string]string{
+				githubHeaderRateRemaining: "4000",
+			},
+			expected: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			credential := &githubPoolCredential{name: "a"}
+			pool := &GithubCredentialPool{credentials: []*githubPoolCredential{credential}}
+
+			response := &http.Response{Header: http.Header{}}
+			for name, value := range testCase.headers {
+				response.Header.Set(name, value)
+			}
+			pool.update(credential, response)
+
+			if credential.known != testCase.expected {
+				t.Fatalf("expected known state %v, got %v", testCase.expected, credential.known)
+			}
+
+			if testCase.expected && (credential.limit != 5000 || credential.remaining != 4000 || !credential.reset.Equal(reset)) {
+				t.Errorf("unexpected rate limit state: limit=%v remaining=%v reset=%v", credential.limit, credential.remaining, credential.reset)
+			}
+		})
+	}
+}
+
+func TestGithubCredentialPoolAggregate(t *testing.T) {
+	now := time.Now()
+	firstReset := now.Add(10 * time.Minute).Truncate(time.Second)
+	secondReset := now.Add(30 * time.Minute).Truncate(time.Second)
+
+	testCases := []struct {
+		name              string
+		credentials       []*githubPoolCredential
+		expectedRemaining string
+		expectedLimit     string
+		expectedReset     string
+	}{
+		{
+			name: "summarized state of all credentials",
+			credentials: []*githubPoolCredential{
+				{name: "a", known: true, limit: 5000, remaining: 0, reset: secondReset},
+				{name: "b", known: true, limit: 5000, remaining: 10, reset: firstReset},
+			},
+			expectedLimit:     "10000",
+			expectedRemaining: "10",
+			expectedReset:     strconv.FormatInt(firstReset.Unix(), 10),
+		},
+		{
+			name: "unknown credential keeps response headers",
+			credentials: []*githubPoolCredential{
+				{name: "a", known: true, limit: 5000, remaining: 0, reset: secondReset},
+				{name: "b"},
+			},
+			expectedLimit:     "5000",
+			expectedRemaining: "0",
+			expectedReset:     "1",
+		},
+		{
+			name: "reset credential keeps response headers",
+			credentials: []*githubPoolCredential{
+				{name: "a", known: true, limit: 5000, remaining: 0, reset: secondReset},
+				{name: "b", known: true, limit: 5000, remaining: 0, reset: now.Add(-time.Minute)},
+			},
+			expectedLimit:     "5000",
+			expectedRemaining: "0",
+			expectedReset:     "1",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pool := &GithubCredentialPool{credentials: testCase.credentials}
+
+			response := &http.Response{Header: http.Header{}}
+			response.Header.Set(githubHeaderRateLimit, "5000")
+			response.Header.Set(githubHeaderRateRemaining, "0")
+			response.Header.Set(githubHeaderRateReset, "1")
+			pool.aggregate(response)
+
+			if value := response.Header.Get(githubHeaderRateLimit); value != testCase.expectedLimit {
+				t.Errorf("expected limit %v, got %v", testCase.expectedLimit, value)
+			}
+			if value := response.Header.Get(githubHeaderRateRemaining); value != testCase.expectedRemaining {
+				t.Errorf("expected remaining %v, got %v", testCase.expectedRemaining, value)
+			}
+			if value := response.Header.Get(githubHeaderRateReset); value != testCase.expectedReset {
+				t.Errorf("expected reset %v, got %v", testCase.expectedReset, value)
+			}
+		})
+	}
+}
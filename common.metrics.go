@@ -0,0 +1,51 @@
+package main
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/webdevops/go-common/prometheus/collector"
+)
+
+type (
+	// MetricList wraps the collector metric list and only passes the labels registered for the metric (configurable label sets)
+	MetricList struct {
+		list   *collector.MetricList
+		labels []string
+	}
+)
+
+// NewMetricList creates a new metric list wrapper with the registered labels of the metric
+func NewMetricList(list *collector.MetricList, labels []string) *MetricList {
+	return &MetricList{list: list, labels: labels}
+}
+
+// filter removes all labels which are not registered for the metric
+// (labels are always projected, same label count doesn't mean same label names)
+func (l *MetricList) filter(labels prometheus.Labels) prometheus.Labels {
+	ret := prometheus.Labels{}
+	for _, labelName := range l.labels {
+		ret[labelName] = labels[labelName]
+	}
+	return ret
+}
+
+// Add adds a metric row with value
+func (l *MetricList) Add(labels prometheus.Labels, value float64) {
+	l.list.Add(l.filter(labels), value)
+}
+
+// AddInfo adds a metric row with value 1
+func (l *MetricList) AddInfo(labels prometheus.Labels) {
+	l.list.AddInfo(l.filter(labels))
+}
+
+// AddTime adds a metric row with time as unix timestamp
+func (l *MetricList) AddTime(labels prometheus.Labels, value time.Time) {
+	l.list.AddTime(l.filter(labels), value)
+}
+
+// AddBool adds a metric row with value 1 (true) or 0 (false)
+func (l *MetricList) AddBool(labels prometheus.Labels, state bool) {
+	l.list.AddBool(l.filter(labels), state)
+}
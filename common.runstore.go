@@ -0,0 +1,278 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/webdevops/go-common/prometheus/collector"
+	"github.com/webdevops/go-common/utils/to"
+)
+
+const (
+	// max size of the persisted run store, run store is only kept in memory if exceeded
+	GITHUB_RUN_STORE_MAX_SIZE = 64 * 1024 * 1024
+)
+
+type (
+	// GithubRunStore keeps the workflow runs of all repositories between collection runs,
+	// so only new and unfinished runs have to be fetched (persisted as local file if path is set)
+	GithubRunStore struct {
+		lock sync.Mutex
+		path string
+
+		Tag          string                               `json:"tag"`
+		Repositories map[string]*GithubRunStoreRepository `json:"repositories"`
+	}
+
+	GithubRunStoreRepository struct {
+		// settings used for fetching the runs (branch filter), runs are fetched again if changed
+		Filter string `json:"filter"`
+
+		// runs created since are stored
+		Since time.Time `json:"since"`
+
+		// high-water mark, latest creation time of stored runs
+		HighWaterMark time.Time `json:"highWaterMark"`
+
+		// last time all runs were fetched
+		LastFullSync time.Time `json:"lastFullSync"`
+
+		Runs []*GithubStoredRun `json:"runs"`
+	}
+
+	// GithubStoredRun is the minimal workflow run (only fields used by the collectors) kept in the run store
+	GithubStoredRun struct {
+		ID             int64      `json:"id"`
+		WorkflowID     int64      `json:"workflowID"`
+		RunNumber      int        `json:"runNumber"`
+		RunAttempt     int        `json:"runAttempt,omitempty"`
+		Name           string     `json:"name,omitempty"`
+		Status         string     `json:"status"`
+		Conclusion     string     `json:"conclusion,omitempty"`
+		Event          string     `json:"event,omitempty"`
+		HeadBranch     string     `json:"headBranch,omitempty"`
+		HeadSHA        string     `json:"headSha,omitempty"`
+		HeadCommitTime *time.Time `json:"headCommitTime,omitempty"`
+		HeadFork       bool       `json:"headFork,omitempty"`
+		ActorLogin     string     `json:"actorLogin,omitempty"`
+		ActorType      string     `json:"actorType,omitempty"`
+		CreatedAt      time.Time  `json:"createdAt"`
+		RunStartedAt   time.Time  `json:"runStartedAt"`
+		UpdatedAt      time.Time  `json:"updatedAt"`
+	}
+)
+
+// NewGithubRunStore creates a new (empty) run store, persisted as local file if path is set
+func NewGithubRunStore(path string, tag string) *GithubRunStore {
+	return &GithubRunStore{
+		path:         path,
+		Tag:          tag,
+		Repositories: map[string]*GithubRunStoreRepository{},
+	}
+}
+
+// githubRunStoreTag returns the tag of the run store, stored runs are ignored if GitHub options changed (same as cache)
+func githubRunStoreTag() string {
+	return to.String(collector.BuildCacheTag(cacheTag, Opts.GitHub))
+}
+
+// Load restores the run store from file (ignored if tag doesn't match)
+func (s *GithubRunStore) Load() error {
+	if s.path == "" {
+		return nil
+	}
+
+	content, err := os.ReadFile(s.path) // #nosec G304 path is configured by user
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	restored := &GithubRunStore{}
+	if err := json.Unmarshal(content, restored); err != nil {
+		return err
+	}
+
+	if restored.Tag != s.Tag {
+		logger.Info(`run store tag mismatch, ignoring stored runs`, slog.String("path", s.path))
+		return nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if restored.Repositories != nil {
+		s.Repositories = restored.Repositories
+	}
+	logger.Info(`restored run store`, slog.String("path", s.path), slog.Int("repositories", len(s.Repositories)))
+
+	return nil
+}
+
+// Save persists the run store to file, not persisted if the run store exceeds GITHUB_RUN_STORE_MAX_SIZE
+func (s *GithubRunStore) Save() error {
+	if s.path == "" {
+		return nil
+	}
+
+	s.lock.Lock()
+	content, err := json.Marshal(s)
+	s.lock.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if len(content) > GITHUB_RUN_STORE_MAX_SIZE {
+		return fmt.Errorf(`run store size of %v bytes exceeds limit of %v bytes, not persisted`, len(content), GITHUB_RUN_STORE_MAX_SIZE)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return err
+	}
+
+	// write to temp file first and rename afterwards (atomic)
+	tmpPath := filepath.Join(filepath.Dir(s.path), "."+filepath.Base(s.path)+".tmp")
+	if err := os.WriteFile(tmpPath, content, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, s.path)
+}
+
+// Get returns the stored runs of a repository, nil if there are none
+func (s *GithubRunStore) Get(key string) *GithubRunStoreRepository {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.Repositories[key]
+}
+
+// Set replaces the stored runs of a repository
+func (s *GithubRunStore) Set(key string, repository *GithubRunStoreRepository) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.Repositories[key] = repository
+}
+
+// Merge adds new runs and replaces updated runs, runs created before since are removed (runs are sorted newest first)
+func (r *GithubRunStoreRepository) Merge(workflowRuns []*github.WorkflowRun, since time.Time) {
+	runs := map[int64]*GithubStoredRun{}
+	for _, storedRun := range r.Runs {
+		runs[storedRun.ID] = storedRun
+	}
+
+	for _, workflowRun := range workflowRuns {
+		if storedRun, exists := runs[workflowRun.GetID()]; exists && storedRun.UpdatedAt.After(workflowRun.GetUpdatedAt().Time) {
+			continue
+		}
+		runs[workflowRun.GetID()] = NewGithubStoredRun(workflowRun)
+	}
+
+	r.Since = since
+	r.Runs = make([]*GithubStoredRun, 0, len(runs))
+	for _, storedRun := range runs {
+		if storedRun.CreatedAt.Before(since) {
+			continue
+		}
+
+		r.Runs = append(r.Runs, storedRun)
+		if storedRun.CreatedAt.After(r.HighWaterMark) {
+			r.HighWaterMark = storedRun.CreatedAt
+		}
+	}
+
+	sort.Slice(r.Runs, func(i, j int) bool {
+		if r.Runs[i].CreatedAt.Equal(r.Runs[j].CreatedAt) {
+			return r.Runs[i].ID > r.Runs[j].ID
+		}
+		return r.Runs[i].CreatedAt.After(r.Runs[j].CreatedAt)
+	})
+}
+
+// Unfinished returns the unfinished runs created before (runs which have to be refreshed)
+func (r *GithubRunStoreRepository) Unfinished(createdBefore time.Time) []*GithubStoredRun {
+	var ret []*GithubStoredRun
+	for _, storedRun := range r.Runs {
+		if storedRun.Status == "completed" || !storedRun.CreatedAt.Before(createdBefore) {
+			continue
+		}
+		ret = append(ret, storedRun)
+	}
+	return ret
+}
+
+// WorkflowRuns returns the stored runs as workflow runs (newest first), the url of the runs is built from the
+// url of the repository
+func (r *GithubRunStoreRepository) WorkflowRuns(repoUrl string) []*github.WorkflowRun {
+	ret := make([]*github.WorkflowRun, 0, len(r.Runs))
+	for _, storedRun := range r.Runs {
+		ret = append(ret, storedRun.WorkflowRun(repoUrl))
+	}
+	return ret
+}
+
+// NewGithubStoredRun creates the minimal stored run of a workflow run
+func NewGithubStoredRun(workflowRun *github.WorkflowRun) *GithubStoredRun {
+	ret := &GithubStoredRun{
+		ID:           workflowRun.GetID(),
+		WorkflowID:   workflowRun.GetWorkflowID(),
+		RunNumber:    workflowRun.GetRunNumber(),
+		RunAttempt:   workflowRun.GetRunAttempt(),
+		Name:         workflowRun.GetName(),
+		Status:       workflowRun.GetStatus(),
+		Conclusion:   workflowRun.GetConclusion(),
+		Event:        workflowRun.GetEvent(),
+		HeadBranch:   workflowRun.GetHeadBranch(),
+		HeadSHA:      workflowRun.GetHeadSHA(),
+		HeadFork:     workflowRun.GetHeadRepository().GetFork(),
+		ActorLogin:   workflowRun.GetActor().GetLogin(),
+		ActorType:    workflowRun.GetActor().GetType(),
+		CreatedAt:    workflowRun.GetCreatedAt().Time,
+		RunStartedAt: workflowRun.GetRunStartedAt().Time,
+		UpdatedAt:    workflowRun.GetUpdatedAt().Time,
+	}
+	if headCommitTime := workflowRun.GetHeadCommit().GetTimestamp(); !headCommitTime.IsZero() {
+		ret.HeadCommitTime = &headCommitTime.Time
+	}
+	return ret
+}
+
+// WorkflowRun converts the stored run into a workflow run (only with the stored fields)
+func (r *GithubStoredRun) WorkflowRun(repoUrl string) *github.WorkflowRun {
+	ret := &github.WorkflowRun{
+		ID:             github.Int64(r.ID),
+		WorkflowID:     github.Int64(r.WorkflowID),
+		RunNumber:      github.Int(r.RunNumber),
+		RunAttempt:     github.Int(r.RunAttempt),
+		Name:           github.String(r.Name),
+		Status:         github.String(r.Status),
+		Event:          github.String(r.Event),
+		HeadBranch:     github.String(r.HeadBranch),
+		HeadSHA:        github.String(r.HeadSHA),
+		HTMLURL:        github.String(fmt.Sprintf("%s/actions/runs/%d", repoUrl, r.ID)),
+		HeadRepository: &github.Repository{Fork: github.Bool(r.HeadFork)},
+		Actor:          &github.User{Login: github.String(r.ActorLogin), Type: github.String(r.ActorType)},
+		CreatedAt:      &github.Timestamp{Time: r.CreatedAt},
+		UpdatedAt:      &github.Timestamp{Time: r.UpdatedAt},
+	}
+	if r.Conclusion != "" {
+		ret.Conclusion = github.String(r.Conclusion)
+	}
+	if !r.RunStartedAt.IsZero() {
+		ret.RunStartedAt = &github.Timestamp{Time: r.RunStartedAt}
+	}
+	if r.HeadCommitTime != nil {
+		ret.HeadCommit = &github.HeadCommit{Timestamp: &github.Timestamp{Time: *r.HeadCommitTime}}
+	}
+	return ret
+}
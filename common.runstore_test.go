@@ -0,0 +1,215 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+)
+
+func testWorkflowRun(id int64, status string, createdAt, updatedAt time.Time) *github.WorkflowRun {
+	return &github.WorkflowRun{
+		ID:         github.Int64(id),
+		WorkflowID: github.Int64(1),
+		Status:     github.String(status),
+		CreatedAt:  &github.Timestamp{Time: createdAt},
+		UpdatedAt:  &github.Timestamp{Time: updatedAt},
+	}
+}
+
+func TestGithubRunStoreRepositoryMerge(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-24 * time.Hour)
+
+	testCases := []struct {
+		name          string
+		stored        []*github.WorkflowRun
+		fetched       []*github.WorkflowRun
+		expectedIDs   []int64
+		expectedState map[int64]string
+		highWaterMark time.Time
+	}{
+		{
+			name: "new runs are sorted newest first",
+			fetched: []*github.WorkflowRun{
+				testWorkflowRun(1, "completed", now.Add(-3*time.Hour), now),
+				testWorkflowRun(3, "completed", now.Add(-1*time.Hour), now),
+				testWorkflowRun(2, "completed", now.Add(-2*time.Hour), now),
+			},
+			expectedIDs:   []int64{3, 2, 1},
+			highWaterMark: now.Add(-1 * time.Hour),
+		},
+		{
+			name: "runs with same creation time are sorted by id",
+			fetched: []*github.WorkflowRun{
+				testWorkflowRun(1, "completed", now.Add(-1*time.Hour), now),
+				testWorkflowRun(2, "completed", now.Add(-1*time.Hour), now),
+			},
+			expectedIDs:   []int64{2, 1},
+			highWaterMark: now.Add(-1 * time.Hour),
+		},
+		{
+			name: "stored runs are kept and updated runs are replaced",
+			stored: []*github.WorkflowRun{
+				testWorkflowRun(1, "completed", now.Add(-3*time.Hour), now.Add(-3*time.Hour)),
+				testWorkflowRun(2, "in_progress", now.Add(-2*time.Hour), now.Add(-2*time.Hour)),
+			},
+			fetched: []*github.WorkflowRun{
+				testWorkflowRun(2, "completed", now.Add(-2*time.Hour), now.Add(-1*time.Hour)),
+			},
+			expectedIDs:   []int64{2, 1},
+			expectedState: map[int64]string{1: "completed", 2: "completed"},
+			highWaterMark: now.Add(-2 * time.Hour),
+		},
+		{
+			name: "older fetched state doesn't replace newer stored state",
+			stored: []*github.WorkflowRun{
+				testWorkflowRun(1, "completed", now.Add(-2*time.Hour), now.Add(-1*time.Hour)),
+			},
+			fetched: []*github.WorkflowRun{
+				testWorkflowRun(1, "in_progress", now.Add(-2*time.Hour), now.Add(-2*time.Hour)),
+			},
+			expectedIDs:   []int64{1},
+			expectedState: map[int64]string{1: "completed"},
+			highWaterMark: now.Add(-2 * time.Hour),
+		},
+		{
+			name: "runs created before since are removed",
+			stored: []*github.WorkflowRun{
+				testWorkflowRun(1, "completed", since.Add(-1*time.Hour), now),
+			},
+			fetched: []*github.WorkflowRun{
+				testWorkflowRun(2, "completed", since.Add(-1*time.Minute), now),
+				testWorkflowRun(3, "completed", since.Add(1*time.Minute), now),
+			},
+			expectedIDs:   []int64{3},
+			highWaterMark: since.Add(1 * time.Minute),
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			storedRuns := &GithubRunStoreRepository{}
+			storedRuns.Merge(testCase.stored, since)
+			storedRuns.Merge(testCase.fetched, since)
+
+			if len(storedRuns.Runs) != len(testCase.expectedIDs) {
+				t.Fatalf("expected %v runs, got %v", len(testCase.expectedIDs), len(storedRuns.Runs))
+			}
+
+			for num, storedRun := range storedRuns.Runs {
+				if storedRun.ID != testCase.expectedIDs[num] {
+					t.Errorf("expected run %v at position %v, got %v", testCase.expectedIDs[num], num, storedRun.ID)
+				}
+
+				if expectedStatus, exists := testCase.expectedState[storedRun.ID]; exists && storedRun.Status != expectedStatus {
+					t.Errorf("expected status %v of run %v, got %v", expectedStatus, storedRun.ID, storedRun.Status)
+				}
+			}
+
+			if !storedRuns.HighWaterMark.Equal(testCase.highWaterMark) {
+				t.Errorf("expected high-water mark %v, got %v", testCase.highWaterMark, storedRuns.HighWaterMark)
+			}
+
+			if !storedRuns.Since.Equal(since) {
+				t.Errorf("expected since %v, got %v", since, storedRuns.Since)
+			}
+		})
+	}
+}
+
+func TestGithubRunStoreRepositoryUnfinished(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+
+	storedRuns := &GithubRunStoreRepository{}
+	storedRuns.Merge([]*github.WorkflowRun{
+		testWorkflowRun(1, "queued", now.Add(-5*time.Hour), now),
+		testWorkflowRun(2, "completed", now.Add(-4*time.Hour), now),
+		testWorkflowRun(3, "in_progress", now.Add(-3*time.Hour), now),
+		testWorkflowRun(4, "in_progress", now.Add(-1*time.Hour), now),
+	}, now.Add(-24*time.Hour))
+
+	testCases := []struct {
+		name          string
+		createdBefore time.Time
+		expectedIDs   []int64
+	}{
+		{name: "all unfinished runs", createdBefore: now, expectedIDs: []int64{4, 3, 1}},
+		{name: "unfinished runs before window", createdBefore: now.Add(-2 * time.Hour), expectedIDs: []int64{3, 1}},
+		{name: "creation time is exclusive", createdBefore: now.Add(-5 * time.Hour), expectedIDs: nil},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			unfinishedRuns := storedRuns.Unfinished(testCase.createdBefore)
+
+			if len(unfinishedRuns) != len(testCase.expectedIDs) {
+				t.Fatalf("expected %v unfinished runs, got %v", len(testCase.expectedIDs), len(unfinishedRuns))
+			}
+
+			for num, storedRun := range unfinishedRuns {
+				if storedRun.ID != testCase.expectedIDs[num] {
+					t.Errorf("expected run %v at position %v, got %v", testCase.expectedIDs[num], num, storedRun.ID)
+				}
+			}
+		})
+	}
+}
+
+func TestGithubStoredRunWorkflowRun(t *testing.T) {
+	createdAt := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	commitTime := createdAt.Add(-1 * time.Hour)
+
+	workflowRun := &github.WorkflowRun{
+		ID:             github.Int64(42),
+		WorkflowID:     github.Int64(7),
+		RunNumber:      github.Int(3),
+		RunAttempt:     github.Int(2),
+		Name:           github.String("build"),
+		Status:         github.String("completed"),
+		Conclusion:     github.String("success"),
+		Event:          github.String("push"),
+		HeadBranch:     github.String("main"),
+		HeadSHA:        github.String("abc"),
+		HeadCommit:     &github.HeadCommit{Timestamp: &github.Timestamp{Time: commitTime}},
+		HeadRepository: &github.Repository{Fork: github.Bool(true)},
+		Actor:          &github.User{Login: github.String("dependabot[bot]"), Type: github.String("Bot")},
+		CreatedAt:      &github.Timestamp{Time: createdAt},
+		UpdatedAt:      &github.Timestamp{Time: createdAt.Add(time.Minute)},
+		Repository:     &github.Repository{Name: github.String("repo")},
+	}
+
+	restoredRun := NewGithubStoredRun(workflowRun).WorkflowRun("https://github.com/org/repo")
+
+	if restoredRun.GetHTMLURL() != "https://github.com/org/repo/actions/runs/42" {
+		t.Errorf("unexpected url %v", restoredRun.GetHTMLURL())
+	}
+
+	if restoredRun.GetID() != 42 || restoredRun.GetWorkflowID() != 7 || restoredRun.GetRunNumber() != 3 || restoredRun.GetRunAttempt() != 2 {
+		t.Errorf("unexpected ids of restored run: %v", restoredRun)
+	}
+
+	if restoredRun.GetConclusion() != "success" || restoredRun.GetEvent() != "push" || restoredRun.GetHeadBranch() != "main" {
+		t.Errorf("unexpected state of restored run: %v", restoredRun)
+	}
+
+	if !restoredRun.GetHeadCommit().GetTimestamp().Time.Equal(commitTime) {
+		t.Errorf("unexpected head commit time %v", restoredRun.GetHeadCommit().GetTimestamp())
+	}
+
+	if !restoredRun.GetHeadRepository().GetFork() {
+		t.Error("expected head repository to be a fork")
+	}
+
+	if restoredRun.GetActor().GetLogin() != "dependabot[bot]" || restoredRun.GetActor().GetType() != "Bot" {
+		t.Errorf("unexpected actor %v", restoredRun.GetActor())
+	}
+
+	if restoredRun.RunStartedAt != nil {
+		t.Error("expected missing run start time to stay unset")
+	}
+
+	if restoredRun.Repository != nil {
+		t.Error("expected repository not to be stored")
+	}
+}
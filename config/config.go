@@ -0,0 +1,626 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/common/model"
+	yaml "go.yaml.in/yaml/v3"
+)
+
+const (
+	CollectorRunning             = "running"
+	CollectorLatestRun           = "latestRun"
+	CollectorConsecutiveFailures = "consecutiveFailures"
+	CollectorDeployments         = "deployments"
+	CollectorPendingDeployments  = "pendingDeployments"
+	CollectorApprovals           = "approvals"
+	CollectorLastRun             = "lastRun"
+	CollectorFlaky               = "flaky"
+	CollectorFailureEpisodes     = "failureEpisodes"
+	CollectorStuck               = "stuck"
+	CollectorAwaitingApproval    = "awaitingApproval"
+	CollectorEvents              = "events"
+	CollectorActors              = "actors"
+)
+
+const (
+	// classes of workflow run conclusions, failing runs are extending and passing runs are ending failure streaks
+	ConclusionFailing = "failing"
+	ConclusionPassing = "passing"
+	ConclusionIgnored = "ignored"
+)
+
+var (
+	// CollectorDefaults defines all available collectors and if they are enabled by default
+	CollectorDefaults = map[string]bool{
+		CollectorRunning:             true,
+		CollectorLatestRun:           true,
+		CollectorConsecutiveFailures: true,
+		CollectorPendingDeployments:  true,
+		CollectorFailureEpisodes:     true,
+		CollectorEvents:              true,
+
+		// needs one request plus one per passing conclusion per workflow without (successful) runs in timeframe (only once per workflow)
+		CollectorLastRun: true,
+
+		// needs one request per previous attempt of re-run workflow runs (only once per attempt)
+		CollectorFlaky: true,
+
+		// needs two requests per repository (unfinished runs created before timeframe)
+		CollectorStuck: true,
+
+		// needs one request per repository (runs awaiting approval of all branches without timeframe)
+		CollectorAwaitingApproval: true,
+
+		// needs requests for workflow runs of all branches (fetched incrementally, same as runs of configured branches)
+		CollectorActors: false,
+
+		// needs one request per unfinished deployment (final states are only fetched once per deployment)
+		CollectorDeployments: false,
+
+		// needs one request per completed workflow run plus two per reviewed environment (only once per run)
+		CollectorApprovals: false,
+	}
+
+	// ConclusionDefaults defines the default class of workflow run conclusions (all other conclusions are ignored)
+	ConclusionDefaults = map[string]string{
+		"failure":         ConclusionFailing,
+		"timed_out":       ConclusionFailing,
+		"startup_failure": ConclusionFailing,
+		"success":         ConclusionPassing,
+	}
+
+	// MetricIdentityLabels defines the labels identifying the series of all metrics (metric name as key),
+	// these labels can't be removed by the label sets of the config file (series would collapse)
+	MetricIdentityLabels = map[string][]string{
+		"github_repository_info":                                     {"org", "repo"},
+		"github_workflow_info":                                       {"org", "repo", "workflowID"},
+		"github_workflow_run_running":                                {"org", "repo", "workflowID", "workflowRunNumber"},
+		"github_workflow_run_running_start_time_seconds":             {"org", "repo", "workflowID", "workflowRunNumber"},
+		"github_workflow_duration_p95_seconds":                       {"org", "repo", "workflowID"},
+		"github_workflow_run_running_age_seconds":                    {"org", "repo", "workflowID", "workflowRunNumber"},
+		"github_workflow_run_running_duration_ratio":                 {"org", "repo", "workflowID", "workflowRunNumber"},
+		"github_workflow_run_stuck":                                  {"org", "repo", "workflowID", "workflowRunNumber"},
+		"github_workflow_run_awaiting_approval":                      {"org", "repo", "workflowID", "workflowRunNumber"},
+		"github_workflow_run_awaiting_approval_age_seconds":          {"org", "repo", "workflowID", "workflowRunNumber"},
+		"github_workflow_latest_run":                                 {"org", "repo", "workflowID", "branch"},
+		"github_workflow_latest_run_start_time_seconds":              {"org", "repo", "workflowID", "workflowRunNumber"},
+		"github_workflow_latest_run_duration_seconds":                {"org", "repo", "workflowID", "workflowRunNumber"},
+		"github_workflow_last_run_timestamp_seconds":                 {"org", "repo", "workflowID"},
+		"github_workflow_last_success_timestamp_seconds":             {"org", "repo", "workflowID"},
+		"github_workflow_never_succeeded":                            {"org", "repo", "workflowID"},
+		"github_workflow_reruns_count":                               {"org", "repo", "workflowID"},
+		"github_workflow_flaky_count":                                {"org", "repo", "workflowID"},
+		"github_workflow_flakiness_ratio":                            {"org", "repo", "workflowID"},
+		"github_workflow_event_runs_count":                           {"org", "repo", "workflowID", "event"},
+		"github_workflow_event_run_duration_seconds":                 {"org", "repo", "workflowID", "event"},
+		"github_workflow_actor_runs_count":                           {"org", "repo", "workflowID", "actorType", "actorLogin"},
+		"github_workflow_actor_failed_runs_count":                    {"org", "repo", "workflowID", "actorType", "actorLogin"},
+		"github_workflow_consecutive_failed_runs":                    {"org", "repo", "workflowID", "branch"},
+		"github_workflow_consecutive_failed_runs_start_time_seconds": {"org", "repo", "workflowID", "branch"},
+		"github_workflow_failure_episode_open_seconds":               {"org", "repo", "workflowID", "branch"},
+		"github_workflow_failure_episode_duration_seconds":           {"org", "repo", "workflowID", "branch"},
+		"github_deployment_count":                                    {"org", "repo", "environment", "state"},
+		"github_dora_deployment_frequency_per_day":                   {"org", "repo", "environment"},
+		"github_dora_lead_time_seconds":                              {"org", "repo", "environment"},
+		"github_dora_change_failure_rate":                            {"org", "repo", "environment"},
+		"github_dora_time_to_restore_seconds":                        {"org", "repo", "environment"},
+		"github_workflow_run_pending_deployment":                     {"org", "repo", "workflowID", "workflowRunNumber", "environment"},
+		"github_workflow_run_pending_deployment_wait_seconds":        {"org", "repo", "workflowID", "workflowRunNumber", "environment"},
+		"github_deployment_pending_count":                            {"org", "repo", "environment"},
+		"github_workflow_approval_count":                             {"org", "repo", "workflowID", "environment", "state"},
+		"github_workflow_approval_latency_seconds":                   {"org", "repo", "workflowID", "environment", "state"},
+	}
+
+	labelNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+type (
+	Config struct {
+		// overrides --github.repository.customprops if set
+		CustomProperties []string `yaml:"customProperties"`
+
+		Defaults      DefaultsConfig       `yaml:"defaults"`
+		Organizations []OrganizationConfig `yaml:"organizations"`
+
+		// label sets per metric (metric name as key)
+		Metrics map[string]MetricConfig `yaml:"metrics"`
+
+		// labels from label mapping file (--config.labelmapping)
+		LabelMapping *LabelMapping `yaml:"-"`
+	}
+
+	// MetricConfig defines which labels are emitted by a metric, either as allow list or as deny list
+	MetricConfig struct {
+		Labels        []string `yaml:"labels"`
+		ExcludeLabels []string `yaml:"excludeLabels"`
+	}
+
+	DefaultsConfig struct {
+		Filter           FilterConfig `yaml:"filter"`
+		WorkflowSettings `yaml:",inline"`
+	}
+
+	OrganizationConfig struct {
+		Name             string       `yaml:"name"`
+		Filter           FilterConfig `yaml:"filter"`
+		WorkflowSettings `yaml:",inline"`
+
+		Repositories []RepositoryConfig `yaml:"repositories"`
+	}
+
+	RepositoryConfig struct {
+		Name             string `yaml:"name"`
+		Enabled          *bool  `yaml:"enabled"`
+		WorkflowSettings `yaml:",inline"`
+	}
+
+	// FilterConfig filters repositories by name (regular expressions)
+	FilterConfig struct {
+		Include []string `yaml:"include"`
+		Exclude []string `yaml:"exclude"`
+
+		include []*regexp.Regexp
+		exclude []*regexp.Regexp
+	}
+
+	// WorkflowSettings can be set as defaults and overridden per organization and repository
+	WorkflowSettings struct {
+		Branches   []string          `yaml:"branches"`
+		Timeframe  *Duration         `yaml:"timeframe"`
+		Collectors map[string]bool   `yaml:"collectors"`
+		Labels     map[string]string `yaml:"labels"`
+
+		Conclusions *ConclusionsConfig `yaml:"conclusions"`
+	}
+
+	// ConclusionsConfig classifies workflow run conclusions (overrides the default class of the conclusions)
+	ConclusionsConfig struct {
+		Failing []string `yaml:"failing"`
+		Passing []string `yaml:"passing"`
+		Ignored []string `yaml:"ignored"`
+	}
+
+	// RepositorySettings are the resolved settings for one repository
+	RepositorySettings struct {
+		Enabled     bool
+		Branches    []string
+		Timeframe   time.Duration
+		Collectors  map[string]bool
+		Labels      map[string]string
+		Conclusions map[string]string
+	}
+
+	// Duration is a time.Duration which also supports days (eg. 30d)
+	Duration time.Duration
+)
+
+// NewConfig creates an empty config (flags only)
+func NewConfig() *Config {
+	return &Config{}
+}
+
+// LoadConfig reads and validates the config file
+func LoadConfig(path string) (*Config, error) {
+	content, err := os.ReadFile(path) // #nosec G304 path is configured by user
+	if err != nil {
+		return nil, err
+	}
+
+	config := NewConfig()
+
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(config); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf(`unable to parse config file "%v": %w`, path, err)
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf(`invalid config file "%v": %w`, path, err)
+	}
+
+	return config, nil
+}
+
+// Validate checks the config and compiles filters
+func (c *Config) Validate() error {
+	for _, customProp := range c.CustomProperties {
+		if !labelNameRegexp.MatchString(customProp) {
+			return fmt.Errorf(`customProperties: invalid property name "%v" (must be usable as label name)`, customProp)
+		}
+	}
+
+	for metricName, metricConfig := range c.Metrics {
+		if err := metricConfig.validate(metricName); err != nil {
+			return fmt.Errorf(`metrics: metric "%v": %w`, metricName, err)
+		}
+	}
+
+	if err := c.Defaults.Filter.compile(); err != nil {
+		return fmt.Errorf(`defaults: %w`, err)
+	}
+
+	if err := c.Defaults.validate(); err != nil {
+		return fmt.Errorf(`defaults: %w`, err)
+	}
+
+	orgNames := map[string]bool{}
+	for num := range c.Organizations {
+		org := &c.Organizations[num]
+
+		if org.Name == "" {
+			return fmt.Errorf(`organizations[%d]: name is required`, num)
+		}
+
+		orgName := strings.ToLower(org.Name)
+		if orgNames[orgName] {
+			return fmt.Errorf(`organizations[%d]: duplicate organization "%v"`, num, org.Name)
+		}
+		orgNames[orgName] = true
+
+		if err := org.Filter.compile(); err != nil {
+			return fmt.Errorf(`organization "%v": %w`, org.Name, err)
+		}
+
+		if err := org.validate(); err != nil {
+			return fmt.Errorf(`organization "%v": %w`, org.Name, err)
+		}
+
+		repoNames := map[string]bool{}
+		for repoNum, repo := range org.Repositories {
+			if repo.Name == "" {
+				return fmt.Errorf(`organization "%v": repositories[%d]: name is required`, org.Name, repoNum)
+			}
+
+			repoName := strings.ToLower(repo.Name)
+			if repoNames[repoName] {
+				return fmt.Errorf(`organization "%v": duplicate repository "%v"`, org.Name, repo.Name)
+			}
+			repoNames[repoName] = true
+
+			if err := repo.validate(); err != nil {
+				return fmt.Errorf(`organization "%v": repository "%v": %w`, org.Name, repo.Name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validate checks the metric name and its label set, labels identifying the series can't be removed
+func (m *MetricConfig) validate(metricName string) error {
+	if !model.LegacyValidation.IsValidMetricName(metricName) {
+		return errors.New(`invalid metric name`)
+	}
+
+	identityLabels, exists := MetricIdentityLabels[metricName]
+	if !exists {
+		return errors.New(`unknown metric`)
+	}
+
+	if len(m.Labels) >= 1 && len(m.ExcludeLabels) >= 1 {
+		return errors.New(`labels and excludeLabels can't be used together`)
+	}
+
+	for _, label := range append(slices.Clone(m.Labels), m.ExcludeLabels...) {
+		if !model.LegacyValidation.IsValidLabelName(label) {
+			return fmt.Errorf(`invalid label name "%v"`, label)
+		}
+	}
+
+	for _, label := range identityLabels {
+		if (len(m.Labels) >= 1 && !slices.Contains(m.Labels, label)) || slices.Contains(m.ExcludeLabels, label) {
+			return fmt.Errorf(`label "%v" identifies the series of the metric and can't be removed`, label)
+		}
+	}
+
+	return nil
+}
+
+// GetCustomProperties returns the repository custom properties used as labels (config file overrides flags)
+func (c *Config) GetCustomProperties(opts *Opts) []string {
+	if c.CustomProperties != nil {
+		return c.CustomProperties
+	}
+	return opts.GitHub.Repositories.CustomProperties
+}
+
+// MetricLabels filters the labels of a metric by the configured label set (order of labels is kept)
+func (c *Config) MetricLabels(metricName string, labels []string) []string {
+	metricConfig, exists := c.Metrics[metricName]
+	if !exists {
+		return labels
+	}
+
+	ret := []string{}
+	for _, label := range labels {
+		switch {
+		case len(metricConfig.Labels) >= 1:
+			if slices.Contains(metricConfig.Labels, label) {
+				ret = append(ret, label)
+			}
+		case !slices.Contains(metricConfig.ExcludeLabels, label):
+			ret = append(ret, label)
+		}
+	}
+
+	return ret
+}
+
+// LabelNames returns all extra label names used in the config (sorted)
+func (c *Config) LabelNames() []string {
+	labelNames := map[string]bool{}
+	if c.LabelMapping != nil {
+		for _, name := range c.LabelMapping.LabelNames() {
+			labelNames[name] = true
+		}
+	}
+	for name := range c.Defaults.Labels {
+		labelNames[name] = true
+	}
+	for _, org := range c.Organizations {
+		for name := range org.Labels {
+			labelNames[name] = true
+		}
+		for _, repo := range org.Repositories {
+			for name := range repo.Labels {
+				labelNames[name] = true
+			}
+		}
+	}
+
+	return sortedKeys(labelNames)
+}
+
+// RepositorySettings resolves the settings for a repository (flags < label mapping < defaults < organization < repository)
+func (c *Config) RepositorySettings(opts *Opts, orgName, repoName string) RepositorySettings {
+	settings := RepositorySettings{
+		Enabled:     true,
+		Timeframe:   opts.GitHub.Workflows.Timeframe,
+		Collectors:  map[string]bool{},
+		Labels:      map[string]string{},
+		Conclusions: map[string]string{},
+	}
+	for name, enabled := range CollectorDefaults {
+		settings.Collectors[name] = enabled
+	}
+	for conclusion, class := range ConclusionDefaults {
+		settings.Conclusions[conclusion] = class
+	}
+
+	if c.LabelMapping != nil {
+		settings.Labels = c.LabelMapping.Labels(orgName, repoName)
+	}
+
+	settings.apply(c.Defaults.WorkflowSettings)
+	settings.Enabled = c.Defaults.Filter.Matches(repoName)
+
+	for _, org := range c.Organizations {
+		if !strings.EqualFold(org.Name, orgName) {
+			continue
+		}
+
+		settings.apply(org.WorkflowSettings)
+		settings.Enabled = settings.Enabled && org.Filter.Matches(repoName)
+
+		for _, repo := range org.Repositories {
+			if !strings.EqualFold(repo.Name, repoName) {
+				continue
+			}
+
+			settings.apply(repo.WorkflowSettings)
+			if repo.Enabled != nil {
+				settings.Enabled = *repo.Enabled
+			}
+		}
+	}
+
+	return settings
+}
+
+// IsCollectorEnabled returns true if collector is enabled for the repository
+func (s *RepositorySettings) IsCollectorEnabled(name string) bool {
+	return s.Collectors[name]
+}
+
+// ConclusionClass returns the class of a workflow run conclusion (failing, passing or ignored)
+func (s *RepositorySettings) ConclusionClass(conclusion string) string {
+	if class, exists := s.Conclusions[conclusion]; exists {
+		return class
+	}
+	return ConclusionIgnored
+}
+
+// ConclusionsOfClass returns the workflow run conclusions of a class (sorted)
+func (s *RepositorySettings) ConclusionsOfClass(class string) []string {
+	ret := []string{}
+	for _, conclusion := range sortedKeys(s.Conclusions) {
+		if s.Conclusions[conclusion] == class {
+			ret = append(ret, conclusion)
+		}
+	}
+	return ret
+}
+
+// MatchesBranch checks if branch matches the configured branches (patterns)
+func (s *RepositorySettings) MatchesBranch(branch string) bool {
+	for _, pattern := range s.Branches {
+		if matched, _ := path.Match(pattern, branch); matched {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *RepositorySettings) apply(settings WorkflowSettings) {
+	if len(settings.Branches) >= 1 {
+		s.Branches = settings.Branches
+	}
+
+	if settings.Timeframe != nil {
+		s.Timeframe = time.Duration(*settings.Timeframe)
+	}
+
+	for name, enabled := range settings.Collectors {
+		s.Collectors[name] = enabled
+	}
+
+	for name, value := range settings.Labels {
+		s.Labels[name] = value
+	}
+
+	if settings.Conclusions != nil {
+		for class, conclusions := range settings.Conclusions.classes() {
+			for _, conclusion := range conclusions {
+				s.Conclusions[conclusion] = class
+			}
+		}
+	}
+}
+
+func (s *WorkflowSettings) validate() error {
+	for _, branch := range s.Branches {
+		if branch == "" {
+			return errors.New(`branches: empty branch name`)
+		}
+
+		if _, err := path.Match(branch, ""); err != nil {
+			return fmt.Errorf(`branches: invalid pattern "%v": %w`, branch, err)
+		}
+	}
+
+	if s.Timeframe != nil && *s.Timeframe <= 0 {
+		return errors.New(`timeframe: must be greater than zero`)
+	}
+
+	for name := range s.Collectors {
+		if _, exists := CollectorDefaults[name]; !exists {
+			return fmt.Errorf(`collectors: unknown collector "%v" (available: %v)`, name, strings.Join(sortedKeys(CollectorDefaults), ", "))
+		}
+	}
+
+	for name := range s.Labels {
+		if !labelNameRegexp.MatchString(name) {
+			return fmt.Errorf(`labels: invalid label name "%v"`, name)
+		}
+	}
+
+	if s.Conclusions != nil {
+		conclusionClasses := map[string]string{}
+		for class, conclusions := range s.Conclusions.classes() {
+			for _, conclusion := range conclusions {
+				if conclusion == "" {
+					return fmt.Errorf(`conclusions.%v: empty conclusion`, class)
+				}
+
+				if otherClass, exists := conclusionClasses[conclusion]; exists {
+					return fmt.Errorf(`conclusions: conclusion "%v" is %v and %v`, conclusion, otherClass, class)
+				}
+				conclusionClasses[conclusion] = class
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *ConclusionsConfig) classes() map[string][]string {
+	return map[string][]string{
+		ConclusionFailing: c.Failing,
+		ConclusionPassing: c.Passing,
+		ConclusionIgnored: c.Ignored,
+	}
+}
+
+func (f *FilterConfig) compile() error {
+	f.include = nil
+	for _, val := range f.Include {
+		filterRegexp, err := regexp.Compile(val)
+		if err != nil {
+			return fmt.Errorf(`filter.include: %w`, err)
+		}
+		f.include = append(f.include, filterRegexp)
+	}
+
+	f.exclude = nil
+	for _, val := range f.Exclude {
+		filterRegexp, err := regexp.Compile(val)
+		if err != nil {
+			return fmt.Errorf(`filter.exclude: %w`, err)
+		}
+		f.exclude = append(f.exclude, filterRegexp)
+	}
+
+	return nil
+}
+
+// Matches checks if the repository name is included and not excluded
+func (f *FilterConfig) Matches(repoName string) bool {
+	if len(f.include) >= 1 {
+		included := false
+		for _, filterRegexp := range f.include {
+			if filterRegexp.MatchString(repoName) {
+				included = true
+				break
+			}
+		}
+
+		if !included {
+			return false
+		}
+	}
+
+	for _, filterRegexp := range f.exclude {
+		if filterRegexp.MatchString(repoName) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// UnmarshalYAML parses durations like 168h, 90m or 30d
+func (d *Duration) UnmarshalYAML(value *yaml.Node) error {
+	val := strings.TrimSpace(value.Value)
+
+	if days, found := strings.CutSuffix(val, "d"); found {
+		num, err := strconv.Atoi(days)
+		if err != nil {
+			return fmt.Errorf(`invalid duration "%v"`, val)
+		}
+		*d = Duration(time.Duration(num) * 24 * time.Hour)
+		return nil
+	}
+
+	duration, err := time.ParseDuration(val)
+	if err != nil {
+		return fmt.Errorf(`invalid duration "%v"`, val)
+	}
+	*d = Duration(duration)
+
+	return nil
+}
+
+// MarshalJSON shows duration as string (eg. for logging)
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(time.Duration(d).String())), nil
+}
+
+func sortedKeys[T any](val map[string]T) []string {
+	ret := make([]string, 0, len(val))
+	for key := range val {
+		ret = append(ret, key)
+	}
+	slices.Sort(ret)
+	return ret
+}
@@ -0,0 +1,337 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeTestFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfig(t *testing.T) {
+	testCases := []struct {
+		name        string
+		content     string
+		expectedErr string
+	}{
+		{
+			name: "empty config",
+		},
+		{
+			name: "full config",
+			content: `
+customProperties: [team]
+defaults:
+  branches: [main, "release/*"]
+  timeframe: 30d
+  collectors:
+    actors: true
+  labels:
+    tier: backend
+  conclusions:
+    ignored: [timed_out]
+  filter:
+    exclude: ["^archive-"]
+organizations:
+  - name: webdevops
+    repositories:
+      - name: exporter
+        enabled: false
+metrics:
+  github_workflow_latest_run:
+    excludeLabels: [tier]
+`,
+		},
+		{
+			name:        "unknown field",
+			content:     "defaults:\n  branch: [main]\n",
+			expectedErr: "field branch not found",
+		},
+		{
+			name:        "invalid duration",
+			content:     "defaults:\n  timeframe: 30x\n",
+			expectedErr: `invalid duration "30x"`,
+		},
+		{
+			name:        "timeframe not positive",
+			content:     "defaults:\n  timeframe: 0d\n",
+			expectedErr: "timeframe: must be greater than zero",
+		},
+		{
+			name:        "invalid branch pattern",
+			content:     "defaults:\n  branches: [\"release/[\"]\n",
+			expectedErr: `branches: invalid pattern "release/["`,
+		},
+		{
+			name:        "unknown collector",
+			content:     "defaults:\n  collectors:\n    unknown: true\n",
+			expectedErr: `collectors: unknown collector "unknown"`,
+		},
+		{
+			name:        "invalid label name",
+			content:     "defaults:\n  labels:\n    tier-name: backend\n",
+			expectedErr: `labels: invalid label name "tier-name"`,
+		},
+		{
+			name:        "conclusion in multiple classes",
+			content:     "defaults:\n  conclusions:\n    failing: [cancelled]\n    ignored: [cancelled]\n",
+			expectedErr: `conclusion "cancelled" is`,
+		},
+		{
+			name:        "invalid filter",
+			content:     "defaults:\n  filter:\n    include: [\"(\"]\n",
+			expectedErr: "defaults: filter.include",
+		},
+		{
+			name:        "organization without name",
+			content:     "organizations:\n  - branches: [main]\n",
+			expectedErr: "organizations[0]: name is required",
+		},
+		{
+			name:        "duplicate organization",
+			content:     "organizations:\n  - name: webdevops\n  - name: WebDevOps\n",
+			expectedErr: `duplicate organization "WebDevOps"`,
+		},
+		{
+			name:        "duplicate repository",
+			content:     "organizations:\n  - name: webdevops\n    repositories:\n      - name: exporter\n      - name: Exporter\n",
+			expectedErr: `duplicate repository "Exporter"`,
+		},
+		{
+			name:        "invalid custom property",
+			content:     "customProperties: [team-name]\n",
+			expectedErr: `invalid property name "team-name"`,
+		},
+		{
+			name:        "invalid metric name",
+			content:     "metrics:\n  github-workflow:\n    labels: [org]\n",
+			expectedErr: `metric "github-workflow": invalid metric name`,
+		},
+		{
+			name:        "unknown metric",
+			content:     "metrics:\n  github_workflow_unknown:\n    labels: [org]\n",
+			expectedErr: `metric "github_workflow_unknown": unknown metric`,
+		},
+		{
+			name:        "labels and excludeLabels",
+			content:     "metrics:\n  github_repository_info:\n    labels: [org, repo]\n    excludeLabels: [topics]\n",
+			expectedErr: "labels and excludeLabels can't be used together",
+		},
+		{
+			name:        "invalid metric label name",
+			content:     "metrics:\n  github_repository_info:\n    excludeLabels: [repo-name]\n",
+			expectedErr: `invalid label name "repo-name"`,
+		},
+		{
+			name:        "identity label excluded",
+			content:     "metrics:\n  github_workflow_latest_run:\n    excludeLabels: [branch]\n",
+			expectedErr: `label "branch" identifies the series of the metric and can't be removed`,
+		},
+		{
+			name:        "identity label missing in label set",
+			content:     "metrics:\n  github_workflow_info:\n    labels: [org, repo]\n",
+			expectedErr: `label "workflowID" identifies the series of the metric and can't be removed`,
+		},
+		{
+			name:    "label set with identity labels",
+			content: "metrics:\n  github_workflow_info:\n    labels: [org, repo, workflowID]\n",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := LoadConfig(writeTestFile(t, "config.yaml", testCase.content))
+
+			switch {
+			case testCase.expectedErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case testCase.expectedErr != "" && err == nil:
+				t.Fatalf("expected error containing %q, got none", testCase.expectedErr)
+			case testCase.expectedErr != "" && !strings.Contains(err.Error(), testCase.expectedErr):
+				t.Fatalf("expected error containing %q, got: %v", testCase.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestConfigRepositorySettings(t *testing.T) {
+	config, err := LoadConfig(writeTestFile(t, "config.yaml", `
+defaults:
+  branches: [main]
+  collectors:
+    deployments: true
+  labels:
+    tier: backend
+    owner: platform
+  conclusions:
+    failing: [cancelled]
+  filter:
+    exclude: ["^archive-"]
+organizations:
+  - name: webdevops
+    timeframe: 30d
+    branches: [main, develop]
+    labels:
+      tier: frontend
+    filter:
+      include: ["^exporter", "^archive-"]
+    repositories:
+      - name: exporter-legacy
+        enabled: false
+        collectors:
+          running: false
+        conclusions:
+          ignored: [timed_out]
+      - name: archive-important
+        enabled: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := &Opts{}
+	opts.GitHub.Workflows.Timeframe = 168 * time.Hour
+
+	testCases := []struct {
+		name               string
+		org                string
+		repo               string
+		enabled            bool
+		branches           []string
+		timeframe          time.Duration
+		labels             map[string]string
+		collectorsEnabled  []string
+		collectorsDisabled []string
+		conclusionClasses  map[string]string
+	}{
+		{
+			name:               "defaults",
+			org:                "other",
+			repo:               "app",
+			enabled:            true,
+			branches:           []string{"main"},
+			timeframe:          168 * time.Hour,
+			labels:             map[string]string{"tier": "backend", "owner": "platform"},
+			collectorsEnabled:  []string{CollectorRunning, CollectorDeployments},
+			collectorsDisabled: []string{CollectorActors, CollectorApprovals},
+			conclusionClasses:  map[string]string{"failure": ConclusionFailing, "cancelled": ConclusionFailing, "success": ConclusionPassing, "skipped": ConclusionIgnored},
+		},
+		{
+			name:     "default filter",
+			org:      "other",
+			repo:     "archive-app",
+			enabled:  false,
+			branches: []string{"main"},
+		},
+		{
+			name:              "organization overrides defaults",
+			org:               "WebDevOps",
+			repo:              "exporter",
+			enabled:           true,
+			branches:          []string{"main", "develop"},
+			timeframe:         30 * 24 * time.Hour,
+			labels:            map[string]string{"tier": "frontend", "owner": "platform"},
+			collectorsEnabled: []string{CollectorRunning, CollectorDeployments},
+		},
+		{
+			name:    "organization filter",
+			org:     "webdevops",
+			repo:    "app",
+			enabled: false,
+		},
+		{
+			name:               "repository overrides organization",
+			org:                "webdevops",
+			repo:               "Exporter-Legacy",
+			enabled:            false,
+			timeframe:          30 * 24 * time.Hour,
+			collectorsDisabled: []string{CollectorRunning},
+			conclusionClasses:  map[string]string{"timed_out": ConclusionIgnored, "cancelled": ConclusionFailing},
+		},
+		{
+			name:    "repository enables filtered repository",
+			org:     "webdevops",
+			repo:    "archive-important",
+			enabled: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			settings := config.RepositorySettings(opts, testCase.org, testCase.repo)
+
+			if settings.Enabled != testCase.enabled {
+				t.Errorf("expected enabled %v, got %v", testCase.enabled, settings.Enabled)
+			}
+
+			if testCase.branches != nil && strings.Join(settings.Branches, ",") != strings.Join(testCase.branches, ",") {
+				t.Errorf("expected branches %v, got %v", testCase.branches, settings.Branches)
+			}
+
+			if testCase.timeframe != 0 && settings.Timeframe != testCase.timeframe {
+				t.Errorf("expected timeframe %v, got %v", testCase.timeframe, settings.Timeframe)
+			}
+
+			for name, value := range testCase.labels {
+				if settings.Labels[name] != value {
+					t.Errorf("expected label %v=%v, got %v", name, value, settings.Labels[name])
+				}
+			}
+
+			for _, name := range testCase.collectorsEnabled {
+				if !settings.IsCollectorEnabled(name) {
+					t.Errorf("expected collector %v to be enabled", name)
+				}
+			}
+
+			for _, name := range testCase.collectorsDisabled {
+				if settings.IsCollectorEnabled(name) {
+					t.Errorf("expected collector %v to be disabled", name)
+				}
+			}
+
+			for conclusion, class := range testCase.conclusionClasses {
+				if settings.ConclusionClass(conclusion) != class {
+					t.Errorf("expected conclusion %v to be %v, got %v", conclusion, class, settings.ConclusionClass(conclusion))
+				}
+			}
+		})
+	}
+}
+
+func TestConfigMetricLabels(t *testing.T) {
+	config := NewConfig()
+	config.Metrics = map[string]MetricConfig{
+		"github_workflow_info":   {Labels: []string{"workflowID", "repo", "org"}},
+		"github_repository_info": {ExcludeLabels: []string{"topics"}},
+	}
+
+	testCases := []struct {
+		metricName string
+		labels     []string
+		expected   []string
+	}{
+		{metricName: "github_workflow_info", labels: []string{"org", "repo", "workflowID", "workflow"}, expected: []string{"org", "repo", "workflowID"}},
+		{metricName: "github_repository_info", labels: []string{"org", "repo", "topics"}, expected: []string{"org", "repo"}},
+		{metricName: "github_workflow_latest_run", labels: []string{"org", "repo"}, expected: []string{"org", "repo"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.metricName, func(t *testing.T) {
+			labels := config.MetricLabels(testCase.metricName, testCase.labels)
+			if strings.Join(labels, ",") != strings.Join(testCase.expected, ",") {
+				t.Errorf("expected labels %v, got %v", testCase.expected, labels)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package config
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	yaml "go.yaml.in/yaml/v3"
+)
+
+type (
+	// LabelMapping maps repositories (name or regular expression) to labels
+	LabelMapping struct {
+		Entries []LabelMappingEntry
+	}
+
+	LabelMappingEntry struct {
+		Repository string            `yaml:"repository"`
+		Labels     map[string]string `yaml:"labels"`
+
+		repository *regexp.Regexp
+	}
+)
+
+// LoadLabelMapping reads a label mapping file, format is detected by file extension (.csv, .yaml or .yml)
+func LoadLabelMapping(path string) (*LabelMapping, error) {
+	content, err := os.ReadFile(path) // #nosec G304 path is configured by user
+	if err != nil {
+		return nil, err
+	}
+
+	mapping := &LabelMapping{}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		err = mapping.parseCSV(content)
+	case ".yaml", ".yml":
+		err = mapping.parseYAML(content)
+	default:
+		return nil, fmt.Errorf(`unsupported label mapping file "%v" (must be .csv, .yaml or .yml)`, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf(`unable to parse label mapping file "%v": %w`, path, err)
+	}
+
+	if err := mapping.Validate(); err != nil {
+		return nil, fmt.Errorf(`invalid label mapping file "%v": %w`, path, err)
+	}
+
+	return mapping, nil
+}
+
+// parseCSV parses csv with header, first column is the repository and all other columns are labels
+func (m *LabelMapping) parseCSV(content []byte) error {
+	reader := csv.NewReader(bytes.NewReader(content))
+	reader.Comment = '#'
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	if len(header) < 2 {
+		return errors.New(`header must contain repository and at least one label column`)
+	}
+
+	for {
+		row, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		entry := LabelMappingEntry{
+			Repository: strings.TrimSpace(row[0]),
+			Labels:     map[string]string{},
+		}
+		for num, labelName := range header[1:] {
+			// empty values are not set, so other entries can provide them
+			if labelValue := strings.TrimSpace(row[num+1]); labelValue != "" {
+				entry.Labels[strings.TrimSpace(labelName)] = labelValue
+			}
+		}
+
+		m.Entries = append(m.Entries, entry)
+	}
+
+	return nil
+}
+
+// parseYAML parses yaml as list of entries
+func (m *LabelMapping) parseYAML(content []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&m.Entries); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// Validate checks the label names and compiles the repository patterns
+func (m *LabelMapping) Validate() error {
+	for num := range m.Entries {
+		entry := &m.Entries[num]
+
+		if entry.Repository == "" {
+			return fmt.Errorf(`entry %d: repository is required`, num+1)
+		}
+
+		// full match, so plain repository names only match themselves
+		repoRegexp, err := regexp.Compile(`^(?:` + entry.Repository + `)$`)
+		if err != nil {
+			return fmt.Errorf(`entry %d: repository: %w`, num+1, err)
+		}
+		entry.repository = repoRegexp
+
+		for name := range entry.Labels {
+			if !labelNameRegexp.MatchString(name) {
+				return fmt.Errorf(`entry %d: invalid label name "%v"`, num+1, name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// LabelNames returns all label names of the mapping
+func (m *LabelMapping) LabelNames() []string {
+	labelNames := map[string]bool{}
+	for _, entry := range m.Entries {
+		for name := range entry.Labels {
+			labelNames[name] = true
+		}
+	}
+	return sortedKeys(labelNames)
+}
+
+// Labels returns the labels of all entries matching the repository ("repo" or "org/repo"), later entries override earlier ones
+func (m *LabelMapping) Labels(orgName, repoName string) map[string]string {
+	labels := map[string]string{}
+	for _, entry := range m.Entries {
+		if !entry.repository.MatchString(repoName) && !entry.repository.MatchString(orgName+"/"+repoName) {
+			continue
+		}
+
+		for name, value := range entry.Labels {
+			labels[name] = value
+		}
+	}
+	return labels
+}
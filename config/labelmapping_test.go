@@ -0,0 +1,136 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadLabelMapping(t *testing.T) {
+	testCases := []struct {
+		name        string
+		file        string
+		content     string
+		expectedErr string
+		labelNames  []string
+		expected    map[string]map[string]string
+	}{
+		{
+			name: "csv",
+			file: "mapping.csv",
+			content: `repository, team, tier
+# comment
+exporter, platform, backend
+webdevops/.*-ui, frontend, frontend
+exporter-legacy, , legacy
+`,
+			labelNames: []string{"team", "tier"},
+			expected: map[string]map[string]string{
+				"exporter":        {"team": "platform", "tier": "backend"},
+				"exporter-legacy": {"tier": "legacy"},
+				"admin-ui":        {"team": "frontend", "tier": "frontend"},
+				"exporter2":       {},
+			},
+		},
+		{
+			name: "yaml with later entries overriding earlier ones",
+			file: "mapping.yml",
+			content: `
+- repository: ".*"
+  labels:
+    team: platform
+- repository: "webdevops/app"
+  labels:
+    team: app
+`,
+			labelNames: []string{"team"},
+			expected: map[string]map[string]string{
+				"app":      {"team": "app"},
+				"app-ui":   {"team": "platform"},
+				"exporter": {"team": "platform"},
+			},
+		},
+		{
+			name:    "empty csv",
+			file:    "mapping.csv",
+			content: "",
+		},
+		{
+			name:        "unsupported format",
+			file:        "mapping.json",
+			content:     "[]",
+			expectedErr: "unsupported label mapping file",
+		},
+		{
+			name:        "csv without label columns",
+			file:        "mapping.csv",
+			content:     "repository\nexporter\n",
+			expectedErr: "header must contain repository and at least one label column",
+		},
+		{
+			name:        "csv with missing columns",
+			file:        "mapping.csv",
+			content:     "repository,team\nexporter\n",
+			expectedErr: "wrong number of fields",
+		},
+		{
+			name:        "yaml with unknown field",
+			file:        "mapping.yaml",
+			content:     "- repo: exporter\n",
+			expectedErr: "field repo not found",
+		},
+		{
+			name:        "missing repository",
+			file:        "mapping.yaml",
+			content:     "- labels:\n    team: platform\n",
+			expectedErr: "entry 1: repository is required",
+		},
+		{
+			name:        "invalid repository pattern",
+			file:        "mapping.csv",
+			content:     "repository,team\nexporter(,platform\n",
+			expectedErr: "entry 1: repository",
+		},
+		{
+			name:        "invalid label name",
+			file:        "mapping.csv",
+			content:     "repository,team-name\nexporter,platform\n",
+			expectedErr: `entry 1: invalid label name "team-name"`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			mapping, err := LoadLabelMapping(writeTestFile(t, testCase.file, testCase.content))
+
+			switch {
+			case testCase.expectedErr == "" && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			case testCase.expectedErr != "" && err == nil:
+				t.Fatalf("expected error containing %q, got none", testCase.expectedErr)
+			case testCase.expectedErr != "" && !strings.Contains(err.Error(), testCase.expectedErr):
+				t.Fatalf("expected error containing %q, got: %v", testCase.expectedErr, err)
+			case testCase.expectedErr != "":
+				return
+			}
+
+			if labelNames := mapping.LabelNames(); strings.Join(labelNames, ",") != strings.Join(testCase.labelNames, ",") {
+				t.Errorf("expected label names %v, got %v", testCase.labelNames, labelNames)
+			}
+
+			for repoName, expectedLabels := range testCase.expected {
+				labels := mapping.Labels("webdevops", repoName)
+
+				if len(labels) != len(expectedLabels) {
+					t.Errorf("expected labels %v for %v, got %v", expectedLabels, repoName, labels)
+					continue
+				}
+
+				for name, value := range expectedLabels {
+					if labels[name] != value {
+						t.Errorf("expected label %v=%v for %v, got %v", name, value, repoName, labels[name])
+					}
+				}
+			}
+		})
+	}
+}
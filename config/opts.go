@@ -2,6 +2,7 @@ package config
 
 import (
 	"encoding/json"
+	"strings"
 	"time"
 )
 
@@ -16,28 +17,62 @@ type (
 			Time   bool   `long:"log.time"     env:"LOG_TIME"    description:"Show log time"`
 		}
 
+		// config file
+		Config struct {
+			Path           string        `long:"config"                  env:"CONFIG"                  description:"Path to config file (YAML) with defaults and per organization/repository overrides"`
+			LabelMapping   string        `long:"config.labelmapping"     env:"CONFIG_LABELMAPPING"     description:"Path to label mapping file (CSV or YAML) with labels per repository (name or regular expression)"`
+			ReloadInterval time.Duration `long:"config.reload.interval"  env:"CONFIG_RELOAD_INTERVAL"  description:"Interval for checking config and label mapping file for changes (0 disables reload on changes, SIGHUP always reloads, reloaded config is applied on start of next collection run, up to scrape.time later)" default:"1m"`
+		}
+
 		// Github
 		GitHub struct {
 			EnterpriseURL string `long:"github.enterprise.url"   env:"GITHUB_ENTERPRISE_URL"  description:"GitHub enterprise url (self hosted)"`
 
-			Organization string `long:"github.organization"     env:"GITHUB_ORGANIZATION"    description:"GitHub organization name" required:"true"`
+			HTTP struct {
+				Timeout time.Duration `long:"github.http.timeout"  env:"GITHUB_HTTP_TIMEOUT"  description:"GitHub request timeout (0 disables timeout)" default:"1m"`
+				Proxy   string        `long:"github.http.proxy"    env:"GITHUB_HTTP_PROXY"    description:"GitHub HTTP(S) proxy url (if not set HTTPS_PROXY, HTTP_PROXY and NO_PROXY env vars are used)"`
+
+				TLS struct {
+					CaFile   *string `long:"github.tls.ca"    env:"GITHUB_TLS_CA"    description:"GitHub TLS: CA bundle (path to PEM file, added to system CAs)"`
+					CertFile *string `long:"github.tls.cert"  env:"GITHUB_TLS_CERT"  description:"GitHub TLS: client certificate (path to PEM file)"`
+					KeyFile  *string `long:"github.tls.key"   env:"GITHUB_TLS_KEY"   description:"GitHub TLS: client certificate key (path to PEM file)"`
+				}
+			}
+
+			Organization string `long:"github.organization"     env:"GITHUB_ORGANIZATION"    description:"GitHub organization name (required for token auth, filter for app installation discovery)"`
 
 			Auth struct {
 				// PAT auth
-				Token string `long:"github.token"            env:"GITHUB_TOKEN"           description:"GitHub token auth: PAT" json:"-"`
+				Token     []string `long:"github.token"            env:"GITHUB_TOKEN"           description:"GitHub token auth: PAT (multiple tokens are used as pool, space delimiter)" env-delim:" " json:"-"`
+				TokenFile []string `long:"github.token.file"       env:"GITHUB_TOKEN_FILE"      description:"GitHub token auth: PAT (path to file, reloaded on changes, multiple files are used as pool, space delimiter)" env-delim:" "`
 
 				// APP auth
-				AppID             *int64  `long:"github.app.id"              env:"GITHUB_APP_ID"               description:"GitHub app auth: App ID"`
-				AppInstallationID *int64  `long:"github.app.installationid"  env:"GITHUB_APP_INSTALLATION_ID"  description:"GitHub app auth: App installation ID"`
-				AppPrivateKeyFile *string `long:"github.app.keyfile"         env:"GITHUB_APP_PRIVATE_KEY"      description:"GitHub app auth: Private key (path to file)"`
+				AppID             *int64  `long:"github.app.id"              env:"GITHUB_APP_ID"                   description:"GitHub app auth: App ID"`
+				AppInstallationID *int64  `long:"github.app.installationid"  env:"GITHUB_APP_INSTALLATION_ID"      description:"GitHub app auth: App installation ID (if not set all installations of the app are discovered)"`
+				AppPrivateKeyFile *string `long:"github.app.keyfile"         env:"GITHUB_APP_PRIVATE_KEY"          description:"GitHub app auth: Private key (path to file, reloaded on changes)"`
+				AppPrivateKey     *string `long:"github.app.key"             env:"GITHUB_APP_PRIVATE_KEY_CONTENT"  description:"GitHub app auth: Private key (PEM or base64 encoded PEM)" json:"-"`
+
+				ReloadInterval time.Duration `long:"github.auth.reload.interval"  env:"GITHUB_AUTH_RELOAD_INTERVAL"  description:"Interval for checking credential files for changes (0 disables reload)" default:"1m"`
 			}
 
 			Repositories struct {
 				CustomProperties []string `long:"github.repository.customprops"         env:"GITHUB_REPOSITORY_CUSTOMPROPS"      description:"GitHub repository custom properties as labels for repos and workflows (space delimiter)" env-delim:" "`
+				TeamSource       string   `long:"github.repository.teams.source"        env:"GITHUB_REPOSITORY_TEAMS_SOURCE"     description:"Source for owning teams of repositories, adds team label to repository, workflow and run metrics" choice:"" choice:"teams" choice:"codeowners"`                                                           // nolint:staticcheck // multiple choices are ok
+				Labels           []string `long:"github.repository.labels"              env:"GITHUB_REPOSITORY_LABELS"           description:"GitHub repository metadata as labels for github_repository_info (space delimiter)" env-delim:" " choice:"topics" choice:"visibility" choice:"language" choice:"fork" choice:"template" choice:"archived"` // nolint:staticcheck // multiple choices are ok
 			}
 
 			Workflows struct {
-				Timeframe time.Duration `long:"github.workflows.timeframe"     env:"GITHUB_WORKFLOWS_TIMEFRAME"    description:"GitHub workflow timeframe for fetching" default:"168h"`
+				Timeframe        time.Duration `long:"github.workflows.timeframe"         env:"GITHUB_WORKFLOWS_TIMEFRAME"          description:"GitHub workflow timeframe for fetching" default:"168h"`
+				FullSyncInterval time.Duration `long:"github.workflows.fullsync.interval" env:"GITHUB_WORKFLOWS_FULLSYNC_INTERVAL"  description:"Interval for fetching all workflow runs of timeframe again (detects re-runs of finished runs), otherwise only new and unfinished runs are fetched (0 always fetches all runs)" default:"6h"`
+
+				Stuck struct {
+					DurationMultiple float64       `long:"github.workflows.stuck.multiple"     env:"GITHUB_WORKFLOWS_STUCK_MULTIPLE"     description:"Running workflow runs are stuck if running longer than multiple of p95 duration of the workflow (0 disables)" default:"3"`
+					MaxDuration      time.Duration `long:"github.workflows.stuck.maxduration"  env:"GITHUB_WORKFLOWS_STUCK_MAXDURATION"  description:"Running workflow runs are stuck if running longer than duration (0 disables)" default:"24h"`
+				}
+
+				Actors struct {
+					PerUser bool `long:"github.workflows.actors.peruser"  env:"GITHUB_WORKFLOWS_ACTORS_PERUSER"  description:"Count workflow runs per user (actorLogin label for users, bots are always counted per login)"`
+				}
 			}
 		}
 
@@ -47,7 +82,8 @@ type (
 
 		// caching
 		Cache struct {
-			Path string `long:"cache.path" env:"CACHE_PATH" description:"Cache path (to folder, file://path... or azblob://storageaccount.blob.core.windows.net/containername or k8scm://{namespace}/{configmap}})"`
+			Path     string `long:"cache.path"     env:"CACHE_PATH"     description:"Cache path (to folder, file://path... or azblob://storageaccount.blob.core.windows.net/containername or k8scm://{namespace}/{configmap}})"`
+			RunStore string `long:"cache.runstore" env:"CACHE_RUNSTORE" description:"Local file for persisting the workflow run store (default: runs.json within cache path if cache path is a local folder)"`
 		}
 
 		Server struct {
@@ -68,6 +104,23 @@ func (o *Opts) GetCachePath(path string) (ret *string) {
 	return
 }
 
+// GetRunStorePath returns the local file of the run store, empty if run store is only kept in memory
+// (run store is only persisted to local files, cache path is only used if it's a local folder)
+func (o *Opts) GetRunStorePath() string {
+	switch {
+	case o.Cache.RunStore != "":
+		return o.Cache.RunStore
+	case o.Cache.Path == "":
+		return ""
+	case strings.HasPrefix(o.Cache.Path, "file://"):
+		return strings.TrimPrefix(o.Cache.Path, "file://") + "/runs.json"
+	case strings.Contains(o.Cache.Path, "://"):
+		return ""
+	default:
+		return o.Cache.Path + "/runs.json"
+	}
+}
+
 func (o *Opts) GetJson() []byte {
 	jsonBytes, err := json.Marshal(o)
 	if err != nil {
@@ -5,16 +5,13 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
-	"log"
 	"log/slog"
 	"net/http"
 	"os"
 	"runtime"
-	"strings"
 
 	_ "github.com/KimMachineGun/automemlimit"
 	ghinstallation "github.com/bradleyfalzon/ghinstallation/v2"
-	github "github.com/google/go-github/v61/github"
 	flags "github.com/jessevdk/go-flags"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/webdevops/go-common/prometheus/collector"
@@ -31,8 +28,6 @@ var (
 	argparser *flags.Parser
 	Opts      config.Opts
 
-	githubClient *github.Client
-
 	// Git version information
 	gitCommit = "<unknown>"
 	gitTag    = "<unknown>"
@@ -56,11 +51,14 @@ func main() {
 
 	initSystem()
 
+	initConfig()
+
 	logger.Infof("init GitHub connection")
 	initGitHubConnection()
 
 	logger.Infof("starting metrics collection")
 	initMetricCollector()
+	startConfigWatcher()
 
 	logger.Infof("starting http server on %s", Opts.Server.Bind)
 	startHttpServer()
@@ -84,63 +82,127 @@ func initArgparser() {
 }
 
 func initGitHubConnection() {
-	var err error
+	ctx := context.Background()
+
+	githubHttpTransport = newGithubHttpTransport()
+
+	// token auth, all tokens are used as pool
+	for num, token := range Opts.GitHub.Auth.Token {
+		if token == "" {
+			continue
+		}
+		githubTokenTransports[fmt.Sprintf("token#%d", num+1)] = NewGithubTokenTransport(githubHttpTransport, token)
+	}
+	for _, tokenFile := range Opts.GitHub.Auth.TokenFile {
+		tokenTransport := NewGithubTokenTransport(githubHttpTransport, "")
+		token, err := readGithubCredentialFile(tokenFile, func(content []byte) error {
+			tokenTransport.SetToken(string(content))
+			return nil
+		})
+		if err != nil {
+			logger.Fatal(`failed to read GitHub token file`, slog.Any("error", err))
+		}
+		tokenTransport.SetToken(string(token))
+		githubTokenTransports[fmt.Sprintf("tokenfile:%s", tokenFile)] = tokenTransport
+	}
 
-	httpClient := &http.Client{}
+	if len(githubTokenTransports) >= 1 {
+		logger.Info(`using GitHub token auth`, slog.Int("tokens", len(githubTokenTransports)))
 
-	if Opts.GitHub.Auth.Token != "" {
-		// token auth
-		logger.Info(`using GitHub token auth`)
-		githubClient = github.NewClient(httpClient)
-		githubClient = githubClient.WithAuthToken(Opts.GitHub.Auth.Token)
-	} else if Opts.GitHub.Auth.AppID != nil {
+		if Opts.GitHub.Organization == "" {
+			logger.Fatal(`GitHub organization not specified, required for token auth`)
+		}
+	}
+
+	if Opts.GitHub.Auth.AppID != nil {
 		// app auth with private key
-		logger.Info(`using GitHub app auth with private key`, slog.Int64("appID", *Opts.GitHub.Auth.AppID), slog.Int64("installationID", *Opts.GitHub.Auth.AppInstallationID))
+		var privateKey []byte
+		switch {
+		case Opts.GitHub.Auth.AppPrivateKey != nil:
+			privateKey = []byte(*Opts.GitHub.Auth.AppPrivateKey)
+		case Opts.GitHub.Auth.AppPrivateKeyFile != nil:
+			var err error
+			privateKey, err = readGithubCredentialFile(*Opts.GitHub.Auth.AppPrivateKeyFile, func(content []byte) error {
+				return githubAppSigner.SetPrivateKey(content)
+			})
+			if err != nil {
+				logger.Fatal(`failed to read GitHub app private key file`, slog.Any("error", err))
+			}
+		default:
+			logger.Fatal(`GitHub app private key not specified`)
+		}
 
-		if Opts.GitHub.Auth.AppPrivateKeyFile == nil {
-			logger.Fatal(`GitHub app private key file not specified`)
+		signer, err := NewGithubAppSigner(privateKey)
+		if err != nil {
+			logger.Fatal(`failed to init GitHub app auth`, slog.Any("error", err))
 		}
+		githubAppSigner = signer
 
-		itr, err := ghinstallation.NewKeyFromFile(http.DefaultTransport, *Opts.GitHub.Auth.AppID, *Opts.GitHub.Auth.AppInstallationID, *Opts.GitHub.Auth.AppPrivateKeyFile)
+		atr, err := ghinstallation.NewAppsTransportWithOptions(githubHttpTransport, *Opts.GitHub.Auth.AppID, ghinstallation.WithSigner(githubAppSigner))
 		if err != nil {
-			log.Fatal(`failed to init GitHub app auth`, slog.Any("error", err))
+			logger.Fatal(`failed to init GitHub app auth`, slog.Any("error", err))
 		}
+		atr.Client = newGithubHttpClient(githubHttpTransport)
 
 		// adapt enterprise url
 		if Opts.GitHub.EnterpriseURL != "" {
-			itr.BaseURL = Opts.GitHub.EnterpriseURL
+			atr.BaseURL = githubEnterpriseApiURL()
+		}
+
+		if Opts.GitHub.Auth.AppInstallationID != nil {
+			// fixed installation
+			logger.Info(`using GitHub app auth with private key`, slog.Int64("appID", *Opts.GitHub.Auth.AppID), slog.Int64("installationID", *Opts.GitHub.Auth.AppInstallationID))
 
-			if !strings.HasSuffix(itr.BaseURL, "/") {
-				itr.BaseURL += "/"
+			if Opts.GitHub.Organization == "" {
+				logger.Fatal(`GitHub organization not specified, required for app auth with installation id`)
 			}
-			if !strings.HasSuffix(itr.BaseURL, "/api/v3/") {
-				itr.BaseURL += "api/v3/"
+
+			githubOrganizations = []*GithubOrganization{
+				newGithubOrganization(Opts.GitHub.Organization, newGithubInstallationTransport(atr, *Opts.GitHub.Auth.AppInstallationID)),
 			}
-		}
+		} else {
+			// installations are discovered
+			logger.Info(`using GitHub app auth with private key and installation discovery`, slog.Int64("appID", *Opts.GitHub.Auth.AppID))
 
-		httpClient.Transport = itr
-		githubClient = github.NewClient(httpClient)
+			githubAppsTransport = atr
+			githubAppsClient = newGithubClient(atr)
+
+			logger.Info(`discovering GitHub app installations`)
+			if err := discoverGithubAppInstallations(ctx); err != nil {
+				logger.Fatal(`unable to discover GitHub app installations`, slog.Any("error", err))
+			}
+
+			if len(githubOrganizations) == 0 {
+				logger.Warn(`no GitHub app installations found, waiting for app to be installed on an organization`)
+			}
+		}
+	} else if len(githubTokenTransports) >= 1 {
+		githubOrganizations = []*GithubOrganization{
+			newGithubOrganization(Opts.GitHub.Organization, nil),
+		}
 	} else {
 		// no auth, failing
 		logger.Fatal(`no GitHub auth specified, either use token or app based auth`)
 	}
 
-	githubClient.UserAgent = fmt.Sprintf(`%s/%s`, UserAgent, gitTag)
+	startGithubCredentialWatcher(Opts.GitHub.Auth.ReloadInterval)
 
-	if Opts.GitHub.EnterpriseURL != "" {
-		githubClient, err = githubClient.WithEnterpriseURLs(Opts.GitHub.EnterpriseURL, "")
+	// test connection
+	// organizations which are not reachable are skipped, only fails if no organization of a fixed organization list is
+	// reachable (installations of discovered apps can be added later)
+	logger.Info(`testing GitHub connection`)
+	foundOrganizations := 0
+	for _, org := range githubOrganizations {
+		_, _, err := org.Client.Organizations.Get(ctx, org.Name)
 		if err != nil {
-			log.Fatal(err)
+			logger.Error(`unable to fetch GitHub organization`, slog.String("org", org.Name), slog.Any("error", err))
+			continue
 		}
+		logger.Info(`found GitHub organization`, slog.String("org", org.Name))
+		foundOrganizations++
 	}
-
-	ctx := context.Background()
-
-	// test connection
-	logger.Info(`testing GitHub connection`)
-	_, _, err = githubClient.Organizations.Get(ctx, Opts.GitHub.Organization)
-	if err != nil {
-		log.Fatalf(`unable to fetch GitHub org "%v": %v`, Opts.GitHub.Organization, err)
+	if foundOrganizations == 0 && githubAppsClient == nil {
+		logger.Fatal(`unable to fetch any GitHub organization`)
 	}
 }
 
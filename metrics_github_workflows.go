@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"strings"
 	"time"
 
 	"github.com/google/go-github/v61/github"
@@ -11,15 +12,33 @@ import (
 	"github.com/webdevops/go-common/prometheus/collector"
 	"github.com/webdevops/go-common/utils/to"
 	"golang.org/x/exp/slices"
+
+	"github.com/webdevops/github-workflow-exporter/config"
 )
 
 const (
-	CUSTOMPROP_LABEL_FMT = "prop_%s"
-	LABEL_VALUE_UNKNOWN  = "<unknown>"
+	CUSTOMPROP_LABEL_FMT  = "prop_%s"
+	CONFIGLABEL_LABEL_FMT = "label_%s"
+	LABEL_VALUE_UNKNOWN   = "<unknown>"
+
+	// max results of workflow run listing, more results must be fetched using smaller time windows
+	GITHUB_WORKFLOW_RUNS_RESULT_LIMIT = 1000
+
+	// incremental sync also fetches runs created shortly before the high-water mark (delayed runs)
+	GITHUB_WORKFLOW_RUNS_SYNC_OVERLAP = 5 * time.Minute
+
+	// min number of completed runs within timeframe for calculating the p95 duration of a workflow
+	GITHUB_WORKFLOW_DURATION_MIN_RUNS = 5
 )
 
 var (
 	githubWorkflowRunningStatus = []string{"in_progress", "action_required", "queued", "waiting", "pending"}
+
+	// status of workflow runs waiting for approval of a maintainer (eg. pull requests from first-time contributors)
+	githubWorkflowAwaitingApprovalStatus = "action_required"
+
+	// status of unfinished workflow runs which can be stuck (also looked up outside of timeframe)
+	githubWorkflowStuckStatus = []string{"queued", "in_progress"}
 )
 
 type (
@@ -33,43 +52,153 @@ type (
 			workflowRunRunning          *prometheus.GaugeVec
 			workflowRunRunningStartTime *prometheus.GaugeVec
 
+			workflowDurationP95             *prometheus.GaugeVec
+			workflowRunRunningAge           *prometheus.GaugeVec
+			workflowRunRunningDurationRatio *prometheus.GaugeVec
+			workflowRunStuck                *prometheus.GaugeVec
+
+			workflowRunAwaitingApproval    *prometheus.GaugeVec
+			workflowRunAwaitingApprovalAge *prometheus.GaugeVec
+
 			workflowLatestRun          *prometheus.GaugeVec
 			workflowLatestRunStartTime *prometheus.GaugeVec
 			workflowLatestRunDuration  *prometheus.GaugeVec
 
-			workflowConsecutiveFailures *prometheus.GaugeVec
+			workflowConsecutiveFailures          *prometheus.GaugeVec
+			workflowConsecutiveFailuresStartTime *prometheus.GaugeVec
+
+			workflowFailureEpisodeOpen     *prometheus.GaugeVec
+			workflowFailureEpisodeDuration *prometheus.SummaryVec
+
+			deploymentCount         *prometheus.GaugeVec
+			doraDeploymentFrequency *prometheus.GaugeVec
+			doraLeadTime            *prometheus.GaugeVec
+			doraChangeFailureRate   *prometheus.GaugeVec
+			doraTimeToRestore       *prometheus.GaugeVec
+
+			workflowRunPendingDeployment     *prometheus.GaugeVec
+			workflowRunPendingDeploymentWait *prometheus.GaugeVec
+			deploymentPendingCount           *prometheus.GaugeVec
+			workflowApprovalCount            *prometheus.GaugeVec
+			workflowApprovalLatency          *prometheus.GaugeVec
+
+			workflowLastRunTimestamp     *prometheus.GaugeVec
+			workflowLastSuccessTimestamp *prometheus.GaugeVec
+			workflowNeverSucceeded       *prometheus.GaugeVec
+
+			workflowReruns         *prometheus.GaugeVec
+			workflowFlaky          *prometheus.GaugeVec
+			workflowFlakinessRatio *prometheus.GaugeVec
+
+			workflowEventRunCount    *prometheus.GaugeVec
+			workflowEventRunDuration *prometheus.SummaryVec
+
+			workflowActorRunCount       *prometheus.GaugeVec
+			workflowActorFailedRunCount *prometheus.GaugeVec
 		}
+
+		// registered metrics and their label sets (for registration after config reload)
+		metricVecs   map[string]prometheus.Collector
+		metricLabels map[string][]string
+
+		// metrics with changed label sets, replacing the registered metrics after the collection run
+		pendingMetricVecs map[string]prometheus.Collector
+
+		// approval reviews of completed workflow runs per repository (key org/repo) and run id
+		approvalReviews map[string]map[int64][]*githubRunApprovalReview
+
+		// finished deployments (final state) per repository (key org/repo) and deployment id
+		finishedDeployments map[string]map[int64]*githubDeployment
+
+		// teams of CODEOWNERS files per repository (key org/repo)
+		codeownersTeams map[string]*githubCodeownersTeams
+
+		// latest (successful) runs of workflows outside of timeframe (key org/repo/workflowID/branches)
+		lastRuns map[string]*githubWorkflowLastRun
+
+		// latest completed runs of workflows without runs within timeframe (key org/repo/workflowID/branches)
+		latestCompletedRuns map[string]*github.WorkflowRun
+
+		// previous attempts of re-run workflow runs per repository (key org/repo) and run attempt (key runID:attempt)
+		runAttempts map[string]map[string]*githubRunAttempt
+
+		// workflow runs of previous collection runs
+		runStore *GithubRunStore
 	}
 )
 
 func (m *MetricsCollectorGithubWorkflows) Setup(collector *collector.Collector) {
 	m.Processor.Setup(collector)
 
+	m.metricLabels = map[string][]string{}
+	m.metricVecs = map[string]prometheus.Collector{}
+	m.pendingMetricVecs = map[string]prometheus.Collector{}
+	m.approvalReviews = map[string]map[int64][]*githubRunApprovalReview{}
+	m.finishedDeployments = map[string]map[int64]*githubDeployment{}
+	m.codeownersTeams = map[string]*githubCodeownersTeams{}
+	m.lastRuns = map[string]*githubWorkflowLastRun{}
+	m.latestCompletedRuns = map[string]*github.WorkflowRun{}
+	m.runAttempts = map[string]map[string]*githubRunAttempt{}
+
+	// run store is persisted in its own local file (not part of the collector cache, which is limited in size for k8scm)
+	runStorePath := Opts.GetRunStorePath()
+	if runStorePath == "" && Opts.Cache.Path != "" {
+		m.Logger().Info(`run store is only kept in memory, use --cache.runstore for persisting to a local file`)
+	}
+	m.runStore = NewGithubRunStore(runStorePath, githubRunStoreTag())
+	if err := m.runStore.Load(); err != nil {
+		m.Logger().Warn(`unable to restore run store, fetching all runs`, slog.Any("error", err))
+	}
+
+	// metrics are served by metricVecsCollector as label sets of metrics can change on config reload,
+	// the prometheus registry doesn't allow registering a metric with another label set again
+	m.Collector.SetPrometheusRegistry(prometheus.NewRegistry())
+	prometheus.MustRegister(&metricVecsCollector{m})
+
+	m.setupMetrics()
+}
+
+// setupMetrics registers all metrics, called on startup and after config reload
+func (m *MetricsCollectorGithubWorkflows) setupMetrics() {
 	var customPropLabels []string
-	for _, customProp := range Opts.GitHub.Repositories.CustomProperties {
+	for _, customProp := range AppConfig.GetCustomProperties(&Opts) {
 		customPropLabels = append(customPropLabels, fmt.Sprintf(CUSTOMPROP_LABEL_FMT, customProp))
 	}
+	for _, labelName := range AppConfig.LabelNames() {
+		customPropLabels = append(customPropLabels, fmt.Sprintf(CONFIGLABEL_LABEL_FMT, labelName))
+	}
+
+	// labels for repository, workflow and run metrics
+	var ownerLabels []string
+	if Opts.GitHub.Repositories.TeamSource != "" {
+		ownerLabels = append(ownerLabels, "team")
+	}
+	customPropLabels = append(ownerLabels, customPropLabels...)
 
 	// ##############################################################3
 	// Infrastructure
 
-	m.prometheus.repository = prometheus.NewGaugeVec(
+	m.prometheus.repository = m.registerGaugeVec(
+		"repository",
 		prometheus.GaugeOpts{
 			Name: "github_repository_info",
 			Help: "GitHub repository info",
 		},
 		append(
-			[]string{
-				"org",
-				"repo",
-				"defaultBranch",
-			},
+			append(
+				[]string{
+					"org",
+					"repo",
+					"defaultBranch",
+				},
+				Opts.GitHub.Repositories.Labels...,
+			),
 			customPropLabels...,
 		),
 	)
-	m.Collector.RegisterMetricList("repository", m.prometheus.repository, true)
 
-	m.prometheus.workflow = prometheus.NewGaugeVec(
+	m.prometheus.workflow = m.registerGaugeVec(
+		"workflow",
 		prometheus.GaugeOpts{
 			Name: "github_workflow_info",
 			Help: "GitHub workflow info",
@@ -87,38 +216,151 @@ func (m *MetricsCollectorGithubWorkflows) Setup(collector *collector.Collector)
 			customPropLabels...,
 		),
 	)
-	m.Collector.RegisterMetricList("workflow", m.prometheus.workflow, true)
 
 	// ##############################################################3
 	// Workflow run running
 
-	m.prometheus.workflowRunRunning = prometheus.NewGaugeVec(
+	m.prometheus.workflowRunRunning = m.registerGaugeVec(
+		"workflowRunRunning",
 		prometheus.GaugeOpts{
 			Name: "github_workflow_run_running",
 			Help: "GitHub workflow running information",
 		},
+		append(
+			[]string{
+				"org",
+				"repo",
+				"workflowID",
+				"workflowRunNumber",
+				"workflow",
+				"workflowUrl",
+				"workflowRun",
+				"workflowRunUrl",
+				"event",
+				"branch",
+				"status",
+				"actorLogin",
+				"actorType",
+			},
+			ownerLabels...,
+		),
+	)
+
+	m.prometheus.workflowRunRunningStartTime = m.registerGaugeVec(
+		"workflowRunRunningStartTime",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_run_running_start_time_seconds",
+			Help: "GitHub workflow run running start time as unix timestamp",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflowRunNumber",
+		},
+	)
+
+	// ##############################################################3
+	// Workflow run stuck
+
+	m.prometheus.workflowDurationP95 = m.registerGaugeVec(
+		"workflowDurationP95",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_duration_p95_seconds",
+			Help: "GitHub workflow p95 duration of completed runs within timeframe in seconds",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflow",
+		},
+	)
+
+	m.prometheus.workflowRunRunningAge = m.registerGaugeVec(
+		"workflowRunRunningAge",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_run_running_age_seconds",
+			Help: "GitHub workflow run (queued or in progress) age since start in seconds",
+		},
 		[]string{
 			"org",
 			"repo",
 			"workflowID",
 			"workflowRunNumber",
 			"workflow",
-			"workflowUrl",
-			"workflowRun",
 			"workflowRunUrl",
-			"event",
 			"branch",
 			"status",
-			"actorLogin",
-			"actorType",
 		},
 	)
-	m.Collector.RegisterMetricList("workflowRunRunning", m.prometheus.workflowRunRunning, true)
 
-	m.prometheus.workflowRunRunningStartTime = prometheus.NewGaugeVec(
+	m.prometheus.workflowRunRunningDurationRatio = m.registerGaugeVec(
+		"workflowRunRunningDurationRatio",
 		prometheus.GaugeOpts{
-			Name: "github_workflow_run_running_start_time_seconds",
-			Help: "GitHub workflow run running start time as unix timestamp",
+			Name: "github_workflow_run_running_duration_ratio",
+			Help: "GitHub workflow run (queued or in progress) age relative to p95 duration of workflow",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflowRunNumber",
+		},
+	)
+
+	m.prometheus.workflowRunStuck = m.registerGaugeVec(
+		"workflowRunStuck",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_run_stuck",
+			Help: "GitHub workflow run is stuck (queued or in progress longer than multiple of p95 duration or max duration)",
+		},
+		append(
+			[]string{
+				"org",
+				"repo",
+				"workflowID",
+				"workflowRunNumber",
+				"workflow",
+				"workflowRunUrl",
+				"branch",
+				"status",
+			},
+			ownerLabels...,
+		),
+	)
+
+	// ##############################################################3
+	// Workflow run awaiting approval
+
+	m.prometheus.workflowRunAwaitingApproval = m.registerGaugeVec(
+		"workflowRunAwaitingApproval",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_run_awaiting_approval",
+			Help: "GitHub workflow run awaiting approval of a maintainer information",
+		},
+		append(
+			[]string{
+				"org",
+				"repo",
+				"workflowID",
+				"workflowRunNumber",
+				"workflow",
+				"workflowRunUrl",
+				"event",
+				"branch",
+				"actorLogin",
+				"actorType",
+			},
+			ownerLabels...,
+		),
+	)
+
+	m.prometheus.workflowRunAwaitingApprovalAge = m.registerGaugeVec(
+		"workflowRunAwaitingApprovalAge",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_run_awaiting_approval_age_seconds",
+			Help: "GitHub workflow run awaiting approval age (since creation) in seconds",
 		},
 		[]string{
 			"org",
@@ -127,90 +369,579 @@ func (m *MetricsCollectorGithubWorkflows) Setup(collector *collector.Collector)
 			"workflowRunNumber",
 		},
 	)
-	m.Collector.RegisterMetricList("workflowRunRunningStartTime", m.prometheus.workflowRunRunningStartTime, true)
 
 	// ##############################################################3
 	// Workflow run latest
 
-	m.prometheus.workflowLatestRun = prometheus.NewGaugeVec(
+	m.prometheus.workflowLatestRun = m.registerGaugeVec(
+		"workflowLatestRun",
 		prometheus.GaugeOpts{
 			Name: "github_workflow_latest_run",
 			Help: "GitHub workflow latest run information",
 		},
+		append(
+			[]string{
+				"org",
+				"repo",
+				"workflowID",
+				"workflowRunNumber",
+				"workflow",
+				"workflowUrl",
+				"workflowRun",
+				"workflowRunUrl",
+				"event",
+				"branch",
+				"conclusion",
+				"actorLogin",
+				"actorType",
+			},
+			ownerLabels...,
+		),
+	)
+
+	m.prometheus.workflowLatestRunStartTime = m.registerGaugeVec(
+		"workflowLatestRunStartTime",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_latest_run_start_time_seconds",
+			Help: "GitHub workflow latest run last executed timestamp",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflowRunNumber",
+		},
+	)
+
+	m.prometheus.workflowLatestRunDuration = m.registerGaugeVec(
+		"workflowLatestRunDuration",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_latest_run_duration_seconds",
+			Help: "GitHub workflow latest run last duration in seconds",
+		},
 		[]string{
 			"org",
 			"repo",
 			"workflowID",
 			"workflowRunNumber",
+		},
+	)
+
+	// ##############################################################3
+	// Workflow last run
+
+	m.prometheus.workflowLastRunTimestamp = m.registerGaugeVec(
+		"workflowLastRunTimestamp",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_last_run_timestamp_seconds",
+			Help: "GitHub workflow last run creation time as unix timestamp (also outside of timeframe)",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflow",
+		},
+	)
+
+	m.prometheus.workflowLastSuccessTimestamp = m.registerGaugeVec(
+		"workflowLastSuccessTimestamp",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_last_success_timestamp_seconds",
+			Help: "GitHub workflow last successful run finish time as unix timestamp (also outside of timeframe)",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflow",
+		},
+	)
+
+	m.prometheus.workflowNeverSucceeded = m.registerGaugeVec(
+		"workflowNeverSucceeded",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_never_succeeded",
+			Help: "GitHub workflow has never run successfully (1) or has (0)",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflow",
+		},
+	)
+
+	// ##############################################################3
+	// Workflow flaky runs
+
+	m.prometheus.workflowReruns = m.registerGaugeVec(
+		"workflowReruns",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_reruns_count",
+			Help: "GitHub workflow count of re-runs within timeframe",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflow",
+		},
+	)
+
+	m.prometheus.workflowFlaky = m.registerGaugeVec(
+		"workflowFlaky",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_flaky_count",
+			Help: "GitHub workflow count of commits which failed and passed afterwards (on same commit sha) within timeframe",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflow",
+		},
+	)
+
+	m.prometheus.workflowFlakinessRatio = m.registerGaugeVec(
+		"workflowFlakinessRatio",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_flakiness_ratio",
+			Help: "GitHub workflow ratio of flaky commits to all commits with finished runs within timeframe",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflow",
+		},
+	)
+
+	// ##############################################################3
+	// Workflow trigger events
+
+	m.prometheus.workflowEventRunCount = m.registerGaugeVec(
+		"workflowEventRunCount",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_event_runs_count",
+			Help: "GitHub workflow count of runs within timeframe per trigger event",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
 			"workflow",
-			"workflowUrl",
-			"workflowRun",
-			"workflowRunUrl",
 			"event",
-			"branch",
-			"conclusion",
+		},
+	)
+
+	m.prometheus.workflowEventRunDuration = m.registerSummaryVec(
+		"workflowEventRunDuration",
+		prometheus.SummaryOpts{
+			Name:       "github_workflow_event_run_duration_seconds",
+			Help:       "GitHub workflow durations of completed runs within timeframe per trigger event in seconds",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+			// observations are reset on every collection run, keep them until next collection run
+			MaxAge: 365 * 24 * time.Hour,
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflow",
+			"event",
+		},
+	)
+
+	// ##############################################################3
+	// Workflow actors
+
+	m.prometheus.workflowActorRunCount = m.registerGaugeVec(
+		"workflowActorRunCount",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_actor_runs_count",
+			Help: "GitHub workflow count of runs of all branches within timeframe per actor type and bot (or user) login",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflow",
+			"actorType",
 			"actorLogin",
+		},
+	)
+
+	m.prometheus.workflowActorFailedRunCount = m.registerGaugeVec(
+		"workflowActorFailedRunCount",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_actor_failed_runs_count",
+			Help: "GitHub workflow count of failed runs of all branches within timeframe per actor type and bot (or user) login",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflow",
 			"actorType",
+			"actorLogin",
 		},
 	)
-	m.Collector.RegisterMetricList("workflowLatestRun", m.prometheus.workflowLatestRun, true)
 
-	m.prometheus.workflowLatestRunStartTime = prometheus.NewGaugeVec(
+	// ##############################################################3
+	// Workflow consecutive failed runs
+
+	m.prometheus.workflowConsecutiveFailures = m.registerGaugeVec(
+		"workflowConsecutiveFailures",
 		prometheus.GaugeOpts{
-			Name: "github_workflow_latest_run_start_time_seconds",
-			Help: "GitHub workflow latest run last executed timestamp",
+			Name: "github_workflow_consecutive_failed_runs",
+			Help: "GitHub workflow consecutive count of failed runs per workflow",
+		},
+		append(
+			[]string{
+				"org",
+				"repo",
+				"workflowID",
+				"workflowRunNumber",
+				"workflow",
+				"workflowUrl",
+				"workflowRun",
+				"workflowRunUrl",
+				"branch",
+				"actorLogin",
+				"actorType",
+			},
+			ownerLabels...,
+		),
+	)
+
+	m.prometheus.workflowConsecutiveFailuresStartTime = m.registerGaugeVec(
+		"workflowConsecutiveFailuresStartTime",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_consecutive_failed_runs_start_time_seconds",
+			Help: "GitHub workflow first failed run of consecutive failed runs creation time as unix timestamp",
 		},
 		[]string{
 			"org",
 			"repo",
 			"workflowID",
+			"branch",
 			"workflowRunNumber",
+			"workflowRunUrl",
+			"headSha",
+			"actorLogin",
+			"actorType",
 		},
 	)
-	m.Collector.RegisterMetricList("workflowLatestRunStartTime", m.prometheus.workflowLatestRunStartTime, true)
 
-	m.prometheus.workflowLatestRunDuration = prometheus.NewGaugeVec(
+	// ##############################################################3
+	// Workflow failure episodes
+
+	m.prometheus.workflowFailureEpisodeOpen = m.registerGaugeVec(
+		"workflowFailureEpisodeOpen",
 		prometheus.GaugeOpts{
-			Name: "github_workflow_latest_run_duration_seconds",
-			Help: "GitHub workflow latest run last duration in seconds",
+			Name: "github_workflow_failure_episode_open_seconds",
+			Help: "GitHub workflow age of open failure episode (since first failed run finished) in seconds",
 		},
 		[]string{
 			"org",
 			"repo",
 			"workflowID",
-			"workflowRunNumber",
+			"workflow",
+			"branch",
+		},
+	)
+
+	m.prometheus.workflowFailureEpisodeDuration = m.registerSummaryVec(
+		"workflowFailureEpisodeDuration",
+		prometheus.SummaryOpts{
+			Name:       "github_workflow_failure_episode_duration_seconds",
+			Help:       "GitHub workflow durations of closed failure episodes (first failed run until next passing run) within timeframe in seconds",
+			Objectives: map[float64]float64{0.5: 0.05, 0.9: 0.01, 0.99: 0.001},
+			// observations are reset on every collection run, keep them until next collection run
+			MaxAge: 365 * 24 * time.Hour,
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflow",
+			"branch",
 		},
 	)
-	m.Collector.RegisterMetricList("workflowLatestRunDuration", m.prometheus.workflowLatestRunDuration, true)
 
 	// ##############################################################3
-	// Workflow consecutive failed runs
+	// Deployments (DORA)
+
+	m.prometheus.deploymentCount = m.registerGaugeVec(
+		"deploymentCount",
+		prometheus.GaugeOpts{
+			Name: "github_deployment_count",
+			Help: "GitHub count of finished deployments within timeframe per environment and state",
+		},
+		[]string{
+			"org",
+			"repo",
+			"environment",
+			"state",
+		},
+	)
+
+	m.prometheus.doraDeploymentFrequency = m.registerGaugeVec(
+		"doraDeploymentFrequency",
+		prometheus.GaugeOpts{
+			Name: "github_dora_deployment_frequency_per_day",
+			Help: "GitHub successful deployments per day within timeframe per environment",
+		},
+		[]string{
+			"org",
+			"repo",
+			"environment",
+		},
+	)
+
+	m.prometheus.doraLeadTime = m.registerGaugeVec(
+		"doraLeadTime",
+		prometheus.GaugeOpts{
+			Name: "github_dora_lead_time_seconds",
+			Help: "GitHub average lead time for changes (commit to successful deployment) within timeframe per environment",
+		},
+		[]string{
+			"org",
+			"repo",
+			"environment",
+		},
+	)
+
+	m.prometheus.doraChangeFailureRate = m.registerGaugeVec(
+		"doraChangeFailureRate",
+		prometheus.GaugeOpts{
+			Name: "github_dora_change_failure_rate",
+			Help: "GitHub ratio of failed deployments within timeframe per environment",
+		},
+		[]string{
+			"org",
+			"repo",
+			"environment",
+		},
+	)
+
+	m.prometheus.doraTimeToRestore = m.registerGaugeVec(
+		"doraTimeToRestore",
+		prometheus.GaugeOpts{
+			Name: "github_dora_time_to_restore_seconds",
+			Help: "GitHub average time from failed to next successful deployment within timeframe per environment",
+		},
+		[]string{
+			"org",
+			"repo",
+			"environment",
+		},
+	)
+
+	// ##############################################################3
+	// Deployment approvals
+
+	m.prometheus.workflowRunPendingDeployment = m.registerGaugeVec(
+		"workflowRunPendingDeployment",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_run_pending_deployment",
+			Help: "GitHub workflow run deployment waiting for approval",
+		},
+		append(
+			[]string{
+				"org",
+				"repo",
+				"workflowID",
+				"workflowRunNumber",
+				"workflow",
+				"workflowRunUrl",
+				"branch",
+				"environment",
+			},
+			ownerLabels...,
+		),
+	)
+
+	m.prometheus.workflowRunPendingDeploymentWait = m.registerGaugeVec(
+		"workflowRunPendingDeploymentWait",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_run_pending_deployment_wait_seconds",
+			Help: "GitHub workflow run deployment waiting for approval since seconds",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflowRunNumber",
+			"environment",
+		},
+	)
+
+	m.prometheus.deploymentPendingCount = m.registerGaugeVec(
+		"deploymentPendingCount",
+		prometheus.GaugeOpts{
+			Name: "github_deployment_pending_count",
+			Help: "GitHub count of deployments waiting for approval per environment",
+		},
+		[]string{
+			"org",
+			"repo",
+			"environment",
+		},
+	)
+
+	m.prometheus.workflowApprovalCount = m.registerGaugeVec(
+		"workflowApprovalCount",
+		prometheus.GaugeOpts{
+			Name: "github_workflow_approval_count",
+			Help: "GitHub count of reviewed deployments of completed workflow runs within timeframe per environment and state",
+		},
+		[]string{
+			"org",
+			"repo",
+			"workflowID",
+			"workflow",
+			"environment",
+			"state",
+		},
+	)
 
-	m.prometheus.workflowConsecutiveFailures = prometheus.NewGaugeVec(
+	m.prometheus.workflowApprovalLatency = m.registerGaugeVec(
+		"workflowApprovalLatency",
 		prometheus.GaugeOpts{
-			Name: "github_workflow_consecutive_failed_runs",
-			Help: "GitHub workflow consecutive count of failed runs per workflow",
+			Name: "github_workflow_approval_latency_seconds",
+			Help: "GitHub average approval/rejection latency of completed workflow runs within timeframe per environment and state",
 		},
 		[]string{
 			"org",
 			"repo",
 			"workflowID",
-			"workflowRunNumber",
 			"workflow",
-			"workflowUrl",
-			"workflowRun",
-			"workflowRunUrl",
-			"branch",
-			"actorLogin",
-			"actorType",
+			"environment",
+			"state",
 		},
 	)
-	m.Collector.RegisterMetricList("workflowConsecutiveFailures", m.prometheus.workflowConsecutiveFailures, true)
+}
+
+// registerGaugeVec registers a gauge metric list with the configured label set
+func (m *MetricsCollectorGithubWorkflows) registerGaugeVec(name string, opts prometheus.GaugeOpts, labels []string) *prometheus.GaugeVec {
+	return m.registerMetricVec(name, opts.Name, labels, func(labels []string) prometheus.Collector {
+		return prometheus.NewGaugeVec(opts, labels)
+	}).(*prometheus.GaugeVec)
+}
+
+// registerSummaryVec registers a summary metric list with the configured label set (observations are reset on every collection run)
+func (m *MetricsCollectorGithubWorkflows) registerSummaryVec(name string, opts prometheus.SummaryOpts, labels []string) *prometheus.SummaryVec {
+	return m.registerMetricVec(name, opts.Name, labels, func(labels []string) prometheus.Collector {
+		return prometheus.NewSummaryVec(opts, labels)
+	}).(*prometheus.SummaryVec)
+}
+
+// registerMetricVec registers a metric list with the configured label set,
+// already registered metrics are only replaced if the label set changed (after the next collection run,
+// until then the previous metric is still served)
+func (m *MetricsCollectorGithubWorkflows) registerMetricVec(name, metricName string, labels []string, newVec func(labels []string) prometheus.Collector) prometheus.Collector {
+	labels = AppConfig.MetricLabels(metricName, labels)
+	if vec, exists := m.metricVecs[name]; exists {
+		if slices.Equal(m.metricLabels[name], labels) {
+			delete(m.pendingMetricVecs, name)
+			return vec
+		}
+
+		m.Logger().Info(`label set of metric changed, replacing metric after next collection run`, slog.String("metric", metricName))
+		vec = newVec(labels)
+		m.metricLabels[name] = labels
+		m.pendingMetricVecs[name] = vec
+
+		return vec
+	}
+
+	vec := newVec(labels)
+	m.Collector.RegisterMetricList(name, vec, true)
+	m.metricLabels[name] = labels
+	m.metricVecs[name] = vec
+
+	return vec
+}
+
+// getMetricList returns the metric list which only passes the registered labels of the metric
+func (m *MetricsCollectorGithubWorkflows) getMetricList(name string) *MetricList {
+	return NewMetricList(m.Collector.GetMetricList(name), m.metricLabels[name])
+}
+
+// swapPendingMetricVecs replaces registered metrics with the metrics of changed label sets,
+// has to be called by collection callback (metric lists are already collected with the new label set)
+func (m *MetricsCollectorGithubWorkflows) swapPendingMetricVecs() {
+	// new collector registry as label sets of already registered metrics can't be changed
+	m.Collector.SetPrometheusRegistry(prometheus.NewRegistry())
+
+	for name, vec := range m.pendingMetricVecs {
+		metricList := m.Collector.GetMetricList(name)
+		m.Collector.RegisterMetricList(name, vec, true).MetricList = metricList.MetricList
+		m.metricVecs[name] = vec
+	}
+	clear(m.pendingMetricVecs)
+}
+
+// metricVecsCollector serves the registered metrics of the collector as unchecked collector
+// (label sets of metrics can change on config reload)
+type metricVecsCollector struct {
+	m *MetricsCollectorGithubWorkflows
+}
+
+func (c *metricVecsCollector) Describe(ch chan<- *prometheus.Desc) {}
+
+func (c *metricVecsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, vec := range c.m.metricVecs {
+		vec.Collect(ch)
+	}
+}
+
+// applyConfig replaces the current config, metrics with changed label sets are replaced after the collection run
+func (m *MetricsCollectorGithubWorkflows) applyConfig(appConfig *config.Config) {
+	lock := collector.Lock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	AppConfig = appConfig
+	m.setupMetrics()
+
+	m.Logger().Info(`applied reloaded config`)
 }
 
 func (m *MetricsCollectorGithubWorkflows) Reset() {}
 
-func (m *MetricsCollectorGithubWorkflows) getRepoList(org string) ([]*github.Repository, error) {
+func (m *MetricsCollectorGithubWorkflows) getRepoList(org *GithubOrganization) ([]*github.Repository, error) {
+	var repositories []*github.Repository
+	var err error
+
+	if org.InstallationID != nil {
+		// app auth, only use repositories granted to the installation
+		repositories, err = m.getInstallationRepoList(org)
+	} else {
+		repositories, err = m.getOrgRepoList(org)
+	}
+	if err != nil {
+		return repositories, err
+	}
+
+	if len(AppConfig.GetCustomProperties(&Opts)) >= 1 {
+		customProperties, err := m.getOrgCustomPropertyValues(org)
+		if err != nil {
+			// custom properties are only labels, repositories are still collected
+			m.Logger().Warn(`unable to fetch custom property values, custom property labels will be empty`, slog.String("org", org.Name), slog.Any("error", err))
+		}
+
+		for _, repository := range repositories {
+			repository.CustomProperties = customProperties[repository.GetName()]
+		}
+	}
+
+	return repositories, nil
+}
+
+func (m *MetricsCollectorGithubWorkflows) getOrgRepoList(org *GithubOrganization) ([]*github.Repository, error) {
 	var repositories []*github.Repository
 
 	opts := github.RepositoryListByOrgOptions{
@@ -218,9 +949,9 @@ func (m *MetricsCollectorGithubWorkflows) getRepoList(org string) ([]*github.Rep
 	}
 
 	for {
-		m.Logger().Debug(`fetching repository list`, slog.Int("page", opts.Page))
+		m.Logger().Debug(`fetching repository list`, slog.String("org", org.Name), slog.Int("page", opts.Page))
 
-		result, response, err := githubClient.Repositories.ListByOrg(m.Context(), org, &opts)
+		result, response, err := org.Client.Repositories.ListByOrg(m.Context(), org.Name, &opts)
 		var ghRateLimitError *github.RateLimitError
 		if ok := errors.As(err, &ghRateLimitError); ok {
 			m.Logger().Debug("request ListByOrg rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
@@ -239,34 +970,46 @@ func (m *MetricsCollectorGithubWorkflows) getRepoList(org string) ([]*github.Rep
 		opts.Page = response.NextPage
 	}
 
-	if len(Opts.GitHub.Repositories.CustomProperties) >= 1 {
-		for _, repository := range repositories {
-			var err error
-			var repoCustomProperties []*github.CustomPropertyValue
-			for {
-				repoCustomProperties, _, err = githubClient.Repositories.GetAllCustomPropertyValues(m.Context(), org, repository.GetName())
-				var ghRateLimitError *github.RateLimitError
-				if ok := errors.As(err, &ghRateLimitError); ok {
-					m.Logger().Debug("request GetAllCustomPropertyValues rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
-					time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
-					continue
-				} else if err != nil {
-					panic(err)
-				}
-				break
-			}
+	return repositories, nil
+}
+
+func (m *MetricsCollectorGithubWorkflows) getInstallationRepoList(org *GithubOrganization) ([]*github.Repository, error) {
+	var repositories []*github.Repository
+
+	opts := github.ListOptions{PerPage: 100, Page: 1}
 
-			repository.CustomProperties = map[string]string{}
-			for _, property := range repoCustomProperties {
-				repository.CustomProperties[property.PropertyName] = property.GetValue()
+	for {
+		m.Logger().Debug(`fetching installation repository list`, slog.String("org", org.Name), slog.Int64("installationID", *org.InstallationID), slog.Int("page", opts.Page))
+
+		result, response, err := org.InstallationClient.Apps.ListRepos(m.Context(), &opts)
+		var ghRateLimitError *github.RateLimitError
+		if ok := errors.As(err, &ghRateLimitError); ok {
+			m.Logger().Debug("request ListRepos rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+			time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+			continue
+		} else if err != nil {
+			return repositories, err
+		}
+
+		for _, repository := range result.Repositories {
+			// installation token is scoped to one account, but make sure to only collect repos of this org
+			if !strings.EqualFold(repository.GetOwner().GetLogin(), org.Name) {
+				continue
 			}
+			repositories = append(repositories, repository)
+		}
+
+		// calc next page
+		if response.NextPage == 0 {
+			break
 		}
+		opts.Page = response.NextPage
 	}
 
 	return repositories, nil
 }
 
-func (m *MetricsCollectorGithubWorkflows) getRepoWorkflows(org, repo string) (map[int64]*github.Workflow, error) {
+func (m *MetricsCollectorGithubWorkflows) getRepoWorkflows(org *GithubOrganization, repo string) (map[int64]*github.Workflow, error) {
 	workflows := map[int64]*github.Workflow{}
 
 	opts := github.ListOptions{PerPage: 100, Page: 1}
@@ -274,7 +1017,7 @@ func (m *MetricsCollectorGithubWorkflows) getRepoWorkflows(org, repo string) (ma
 	for {
 		m.Logger().Debug(`fetching workflows list for repository`, slog.String("repository", repo), slog.Int("page", opts.Page))
 
-		result, response, err := githubClient.Actions.ListWorkflows(m.Context(), org, repo, &opts)
+		result, response, err := org.Client.Actions.ListWorkflows(m.Context(), org.Name, repo, &opts)
 		var ghRateLimitError *github.RateLimitError
 		if ok := errors.As(err, &ghRateLimitError); ok {
 			m.Logger().Debug("request ListWorkflows rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
@@ -298,20 +1041,83 @@ func (m *MetricsCollectorGithubWorkflows) getRepoWorkflows(org, repo string) (ma
 	return workflows, nil
 }
 
-func (m *MetricsCollectorGithubWorkflows) getRepoWorkflowRuns(repo *github.Repository) ([]*github.WorkflowRun, error) {
+// getRepoWorkflowRuns returns the workflow runs of the timeframe (newest first) using the run store,
+// only new and unfinished runs are fetched (all runs are fetched again on full sync)
+func (m *MetricsCollectorGithubWorkflows) getRepoWorkflowRuns(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings) ([]*github.WorkflowRun, error) {
+	storeKey := fmt.Sprintf("%s/%s", org.Name, repo.GetName())
+	branch, filterBranches := workflowRunBranchFilter(repo, settings)
+	storeFilter := fmt.Sprintf("%s:%s", branch, strings.Join(settings.Branches, ","))
+
+	return m.getRepoStoredWorkflowRuns(org, repo, settings, storeKey, storeFilter, branch, filterBranches)
+}
+
+// getRepoAllBranchesWorkflowRuns fetches the workflow runs of all branches within the timeframe (without branch settings),
+// stored separately in the run store
+func (m *MetricsCollectorGithubWorkflows) getRepoAllBranchesWorkflowRuns(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings) ([]*github.WorkflowRun, error) {
+	storeKey := fmt.Sprintf("%s/%s/*", org.Name, repo.GetName())
+	return m.getRepoStoredWorkflowRuns(org, repo, settings, storeKey, "*", "", false)
+}
+
+// getRepoStoredWorkflowRuns fetches new and unfinished workflow runs (incremental sync) or all workflow runs
+// of the timeframe (full sync) and merges them into the run store
+func (m *MetricsCollectorGithubWorkflows) getRepoStoredWorkflowRuns(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings, storeKey, storeFilter, branch string, filterBranches bool) ([]*github.WorkflowRun, error) {
+	now := time.Now()
+	since := now.Add(-settings.Timeframe)
+
+	fetchFrom := since
+	storedRuns := m.runStore.Get(storeKey)
+	if storedRuns != nil &&
+		storedRuns.Filter == storeFilter &&
+		!storedRuns.Since.After(since) &&
+		now.Sub(storedRuns.LastFullSync) < Opts.GitHub.Workflows.FullSyncInterval {
+		// incremental sync, fetch runs since high-water mark (unfinished runs before are refreshed separately)
+		fetchFrom = storedRuns.HighWaterMark.Add(-GITHUB_WORKFLOW_RUNS_SYNC_OVERLAP)
+		if fetchFrom.Before(since) {
+			fetchFrom = since
+		}
+	} else {
+		// full sync
+		storedRuns = &GithubRunStoreRepository{
+			Filter:       storeFilter,
+			LastFullSync: now,
+		}
+	}
+
+	workflowRuns, err := m.getRepoWorkflowRunsWindow(org, repo, settings, branch, filterBranches, fetchFrom, now)
+	if err != nil {
+		return workflowRuns, err
+	}
+
+	if unfinishedRuns := storedRuns.Unfinished(fetchFrom); len(unfinishedRuns) >= 1 {
+		refreshedRuns, err := m.refreshUnfinishedWorkflowRuns(org, repo, settings, branch, filterBranches, unfinishedRuns, since, fetchFrom)
+		if err != nil {
+			return workflowRuns, err
+		}
+		workflowRuns = append(workflowRuns, refreshedRuns...)
+	}
+
+	storedRuns.Merge(workflowRuns, since)
+	m.runStore.Set(storeKey, storedRuns)
+
+	return storedRuns.WorkflowRuns(repo.GetHTMLURL()), nil
+}
+
+// getRepoWorkflowRunsWindow fetches the workflow runs created within the time window (newest first),
+// windows with more runs than the API is able to return are split into smaller windows
+func (m *MetricsCollectorGithubWorkflows) getRepoWorkflowRunsWindow(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings, branch string, filterBranches bool, createdFrom, createdTo time.Time) ([]*github.WorkflowRun, error) {
 	var workflowRuns []*github.WorkflowRun
 
 	opts := github.ListWorkflowRunsOptions{
-		Branch:              repo.GetDefaultBranch(),
+		Branch:              branch,
 		ExcludePullRequests: true,
 		ListOptions:         github.ListOptions{PerPage: 100, Page: 1},
-		Created:             ">=" + time.Now().Add(-Opts.GitHub.Workflows.Timeframe).Format(time.RFC3339),
+		Created:             createdFrom.UTC().Format(time.RFC3339) + ".." + createdTo.UTC().Format(time.RFC3339),
 	}
 
 	for {
-		m.Logger().Debug(`fetching list of workflow runs for repository`, slog.String("repository", repo.GetName()), slog.Int("page", opts.Page))
+		m.Logger().Debug(`fetching list of workflow runs for repository`, slog.String("repository", repo.GetName()), slog.String("created", opts.Created), slog.Int("page", opts.Page))
 
-		result, response, err := githubClient.Actions.ListRepositoryWorkflowRuns(m.Context(), Opts.GitHub.Organization, *repo.Name, &opts)
+		result, response, err := org.Client.Actions.ListRepositoryWorkflowRuns(m.Context(), org.Name, repo.GetName(), &opts)
 		var ghRateLimitError *github.RateLimitError
 		if ok := errors.As(err, &ghRateLimitError); ok {
 			m.Logger().Debug("request ListRepositoryWorkflowRuns rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
@@ -321,7 +1127,26 @@ func (m *MetricsCollectorGithubWorkflows) getRepoWorkflowRuns(repo *github.Repos
 			return workflowRuns, err
 		}
 
-		workflowRuns = append(workflowRuns, result.WorkflowRuns...)
+		// API only returns the first results, split window into two windows (newer window first to keep order)
+		windowSplit := createdFrom.Add(createdTo.Sub(createdFrom) / 2).Truncate(time.Second)
+		if opts.Page == 1 && result.GetTotalCount() > GITHUB_WORKFLOW_RUNS_RESULT_LIMIT && windowSplit.After(createdFrom) {
+			m.Logger().Debug(`too many workflow runs for time window, splitting window`, slog.String("repository", repo.GetName()), slog.String("created", opts.Created), slog.Int("totalCount", result.GetTotalCount()))
+
+			newerWorkflowRuns, err := m.getRepoWorkflowRunsWindow(org, repo, settings, branch, filterBranches, windowSplit.Add(time.Second), createdTo)
+			if err != nil {
+				return newerWorkflowRuns, err
+			}
+
+			olderWorkflowRuns, err := m.getRepoWorkflowRunsWindow(org, repo, settings, branch, filterBranches, createdFrom, windowSplit)
+			return append(newerWorkflowRuns, olderWorkflowRuns...), err
+		}
+
+		for _, workflowRun := range result.WorkflowRuns {
+			if filterBranches && !settings.MatchesBranch(workflowRun.GetHeadBranch()) {
+				continue
+			}
+			workflowRuns = append(workflowRuns, workflowRun)
+		}
 
 		// calc next page
 		if response.NextPage == 0 {
@@ -333,20 +1158,165 @@ func (m *MetricsCollectorGithubWorkflows) getRepoWorkflowRuns(repo *github.Repos
 	return workflowRuns, nil
 }
 
+// refreshUnfinishedWorkflowRuns fetches the current state of stored unfinished runs created before the incremental
+// sync window, runs with unchanged status are found by one request per status, changed runs are fetched by id
+func (m *MetricsCollectorGithubWorkflows) refreshUnfinishedWorkflowRuns(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings, branch string, filterBranches bool, unfinishedRuns []*GithubStoredRun, createdFrom, createdTo time.Time) ([]*github.WorkflowRun, error) {
+	var statusList []string
+	for _, storedRun := range unfinishedRuns {
+		if !slices.Contains(statusList, storedRun.Status) {
+			statusList = append(statusList, storedRun.Status)
+		}
+	}
+
+	created := createdFrom.UTC().Format(time.RFC3339) + ".." + createdTo.UTC().Format(time.RFC3339)
+	workflowRuns, err := m.getRepoWorkflowRunsByStatus(org, repo, settings, branch, filterBranches, statusList, created)
+	if err != nil {
+		return workflowRuns, err
+	}
+
+	unchangedRuns := map[int64]bool{}
+	for _, workflowRun := range workflowRuns {
+		unchangedRuns[workflowRun.GetID()] = true
+	}
+
+	for _, storedRun := range unfinishedRuns {
+		if unchangedRuns[storedRun.ID] {
+			continue
+		}
+
+		workflowRun, err := m.getWorkflowRun(org, repo, storedRun.ID)
+		if err != nil {
+			// eg. deleted runs, stored run is kept until it's out of the timeframe
+			m.Logger().Warn(`unable to refresh unfinished workflow run`, slog.String("repository", repo.GetName()), slog.Int64("workflowRunID", storedRun.ID), slog.Any("error", err))
+			continue
+		}
+		workflowRuns = append(workflowRuns, workflowRun)
+	}
+
+	return workflowRuns, nil
+}
+
+// getWorkflowRun fetches a workflow run by id
+func (m *MetricsCollectorGithubWorkflows) getWorkflowRun(org *GithubOrganization, repo *github.Repository, workflowRunID int64) (*github.WorkflowRun, error) {
+	for {
+		m.Logger().Debug(`fetching workflow run`, slog.String("repository", repo.GetName()), slog.Int64("workflowRunID", workflowRunID))
+
+		workflowRun, _, err := org.Client.Actions.GetWorkflowRunByID(m.Context(), org.Name, repo.GetName(), workflowRunID)
+		var ghRateLimitError *github.RateLimitError
+		if ok := errors.As(err, &ghRateLimitError); ok {
+			m.Logger().Debug("request GetWorkflowRunByID rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+			time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+			continue
+		}
+
+		return workflowRun, err
+	}
+}
+
+// getRepoWorkflowRunsByStatus fetches the workflow runs with status and creation filter (eg. "<2006-01-02T15:04:05Z"),
+// used for unfinished runs which are not covered by the fetched runs
+func (m *MetricsCollectorGithubWorkflows) getRepoWorkflowRunsByStatus(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings, branch string, filterBranches bool, statusList []string, created string) ([]*github.WorkflowRun, error) {
+	var workflowRuns []*github.WorkflowRun
+
+	for _, status := range statusList {
+		opts := github.ListWorkflowRunsOptions{
+			Branch:              branch,
+			Status:              status,
+			ExcludePullRequests: true,
+			ListOptions:         github.ListOptions{PerPage: 100, Page: 1},
+			Created:             created,
+		}
+
+		for {
+			m.Logger().Debug(`fetching list of workflow runs by status for repository`, slog.String("repository", repo.GetName()), slog.String("status", status), slog.Int("page", opts.Page))
+
+			result, response, err := org.Client.Actions.ListRepositoryWorkflowRuns(m.Context(), org.Name, repo.GetName(), &opts)
+			var ghRateLimitError *github.RateLimitError
+			if ok := errors.As(err, &ghRateLimitError); ok {
+				m.Logger().Debug("request ListRepositoryWorkflowRuns rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+				time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+				continue
+			} else if err != nil {
+				return workflowRuns, err
+			}
+
+			for _, workflowRun := range result.WorkflowRuns {
+				if filterBranches && !settings.MatchesBranch(workflowRun.GetHeadBranch()) {
+					continue
+				}
+				workflowRuns = append(workflowRuns, workflowRun)
+			}
+
+			// calc next page
+			if response.NextPage == 0 {
+				break
+			}
+			opts.Page = response.NextPage
+		}
+	}
+
+	return workflowRuns, nil
+}
+
 func (m *MetricsCollectorGithubWorkflows) Collect(callback chan<- func()) {
-	repositoryMetric := m.Collector.GetMetricList("repository")
-	workflowMetric := m.Collector.GetMetricList("workflow")
+	// apply reloaded config
+	if appConfig := takePendingConfig(); appConfig != nil {
+		m.applyConfig(appConfig)
+	}
+
+	// replace metrics with changed label sets after collection, also on panics as the metric lists are already
+	// collected with the new label sets (callbacks are run before metrics are set)
+	defer func() {
+		if len(m.pendingMetricVecs) >= 1 {
+			callback <- m.swapPendingMetricVecs
+		}
+	}()
+
+	// discover new or removed app installations
+	if githubAppsClient != nil {
+		if err := discoverGithubAppInstallations(m.Context()); err != nil {
+			m.Logger().Warn(`unable to discover GitHub app installations, using previous list`, slog.Any("error", err))
+		}
+	}
+
+	for _, org := range getGithubOrganizations() {
+		m.collectOrganization(org, callback)
+	}
+
+	if err := m.runStore.Save(); err != nil {
+		m.Logger().Warn(`unable to save run store`, slog.Any("error", err))
+	}
+}
+
+func (m *MetricsCollectorGithubWorkflows) collectOrganization(org *GithubOrganization, callback chan<- func()) {
+	repositoryMetric := m.getMetricList("repository")
+	workflowMetric := m.getMetricList("workflow")
 
-	org := Opts.GitHub.Organization
+	m.Logger().Debug(`collecting organization`, slog.String("org", org.Name))
 
+	// errors of one organization (eg. missing permissions of an installation) must not stop the other organizations
 	repositories, err := m.getRepoList(org)
 	if err != nil {
-		panic(err)
+		m.Logger().Error(`unable to fetch repositories, skipping organization`, slog.String("org", org.Name), slog.Any("error", err))
+		return
+	}
+
+	var teamRepositories map[string][]string
+	if Opts.GitHub.Repositories.TeamSource == TEAM_SOURCE_TEAMS {
+		teamRepositories, err = m.getOrgTeamRepositories(org)
+		if err != nil {
+			m.Logger().Warn(`unable to fetch teams, team label will be empty`, slog.String("org", org.Name), slog.Any("error", err))
+		}
 	}
 
 	for _, repo := range repositories {
-		// skip archived or disabled repos
-		if repo.GetArchived() || repo.GetDisabled() {
+		// skip disabled repos
+		if repo.GetDisabled() {
+			continue
+		}
+
+		// skip archived repos (only reported as repository info if archived label is enabled)
+		if repo.GetArchived() && !slices.Contains(Opts.GitHub.Repositories.Labels, "archived") {
 			continue
 		}
 
@@ -356,10 +1326,16 @@ func (m *MetricsCollectorGithubWorkflows) Collect(callback chan<- func()) {
 			continue
 		}
 
+		// skip repos disabled by config
+		settings := AppConfig.RepositorySettings(&Opts, org.Name, repo.GetName())
+		if !settings.Enabled {
+			continue
+		}
+
 		// build custom properties
 		propLabels := prometheus.Labels{}
-		if len(Opts.GitHub.Repositories.CustomProperties) >= 1 {
-			for _, customProp := range Opts.GitHub.Repositories.CustomProperties {
+		if customProps := AppConfig.GetCustomProperties(&Opts); len(customProps) >= 1 {
+			for _, customProp := range customProps {
 				labelName := fmt.Sprintf(CUSTOMPROP_LABEL_FMT, customProp)
 				propLabels[labelName] = ""
 
@@ -369,27 +1345,58 @@ func (m *MetricsCollectorGithubWorkflows) Collect(callback chan<- func()) {
 			}
 		}
 
+		// build config labels
+		for _, configLabel := range AppConfig.LabelNames() {
+			labelName := fmt.Sprintf(CONFIGLABEL_LABEL_FMT, configLabel)
+			propLabels[labelName] = settings.Labels[configLabel]
+		}
+
+		// build owner labels (also used for run metrics)
+		ownerLabels := prometheus.Labels{}
+		switch Opts.GitHub.Repositories.TeamSource {
+		case TEAM_SOURCE_TEAMS:
+			ownerLabels["team"] = teamLabelValue(teamRepositories[repo.GetName()])
+		case TEAM_SOURCE_CODEOWNERS:
+			teams, err := m.getRepoCodeownersTeams(org, repo)
+			if err != nil {
+				m.Logger().Warn(`unable to fetch CODEOWNERS, team label will be empty`, slog.String("repository", repo.GetName()), slog.Any("error", err))
+			}
+			ownerLabels["team"] = teamLabelValue(teams)
+		}
+		for labelName, labelValue := range ownerLabels {
+			propLabels[labelName] = labelValue
+		}
+
 		// repo info metric
 		labels := prometheus.Labels{
-			"org":           org,
+			"org":           org.Name,
 			"repo":          repo.GetName(),
 			"defaultBranch": to.String(repo.DefaultBranch),
 		}
 		for labelName, labelValue := range propLabels {
 			labels[labelName] = labelValue
 		}
+		for _, labelName := range Opts.GitHub.Repositories.Labels {
+			labels[labelName] = repositoryMetadataLabel(repo, labelName)
+		}
 		repositoryMetric.AddInfo(labels)
 
+		// archived repos don't have any workflow runs
+		if repo.GetArchived() {
+			continue
+		}
+
 		// get workflows
 		workflows, err := m.getRepoWorkflows(org, repo.GetName())
 		if err != nil {
-			panic(err)
+			m.Logger().Error(`unable to fetch workflows, skipping repository`, slog.String("org", org.Name), slog.String("repository", repo.GetName()), slog.Any("error", err))
+			continue
 		}
 
 		// workflow info metrics
 		for _, workflow := range workflows {
 			labels := prometheus.Labels{
-				"org":         org,
+				"org":         org.Name,
 				"repo":        repo.GetName(),
 				"workflowID":  fmt.Sprintf("%v", workflow.GetID()),
 				"workflow":    workflow.GetName(),
@@ -403,24 +1410,81 @@ func (m *MetricsCollectorGithubWorkflows) Collect(callback chan<- func()) {
 			workflowMetric.AddInfo(labels)
 		}
 
+		var workflowRuns []*github.WorkflowRun
 		if len(workflows) >= 1 {
-			workflowRuns, err := m.getRepoWorkflowRuns(repo)
+			workflowRuns, err = m.getRepoWorkflowRuns(org, repo, &settings)
 			if err != nil {
-				panic(err)
+				m.Logger().Error(`unable to fetch workflow runs, skipping repository`, slog.String("org", org.Name), slog.String("repository", repo.GetName()), slog.Any("error", err))
+				continue
 			}
 
 			if len(workflowRuns) >= 1 {
-				m.collectRunningRuns(Opts.GitHub.Organization, repo, workflows, workflowRuns, callback)
-				m.collectLatestRun(Opts.GitHub.Organization, repo, workflows, workflowRuns, callback)
-				m.collectConsecutiveFailures(Opts.GitHub.Organization, repo, workflows, workflowRuns, callback)
+				if settings.IsCollectorEnabled(config.CollectorRunning) {
+					m.collectRunningRuns(org.Name, repo, workflows, workflowRuns, ownerLabels, callback)
+				}
+
+				if settings.IsCollectorEnabled(config.CollectorConsecutiveFailures) {
+					m.collectConsecutiveFailures(org.Name, repo, &settings, workflows, workflowRuns, ownerLabels, callback)
+				}
+
+				if settings.IsCollectorEnabled(config.CollectorFailureEpisodes) {
+					m.collectFailureEpisodes(org.Name, repo, &settings, workflows, workflowRuns, callback)
+				}
+
+				if settings.IsCollectorEnabled(config.CollectorFlaky) {
+					m.collectFlakyRuns(org, repo, &settings, workflows, workflowRuns, callback)
+				}
+
+				if settings.IsCollectorEnabled(config.CollectorEvents) {
+					m.collectEventRuns(org.Name, repo, workflows, workflowRuns, callback)
+				}
+
+				if settings.IsCollectorEnabled(config.CollectorPendingDeployments) {
+					m.collectPendingDeployments(org, repo, workflows, workflowRuns, ownerLabels, callback)
+				}
+
+				if settings.IsCollectorEnabled(config.CollectorApprovals) {
+					m.collectApprovals(org, repo, workflows, workflowRuns, callback)
+				}
+			}
+
+			// also collected without runs, workflow runs are looked up without timeframe
+			if settings.IsCollectorEnabled(config.CollectorLatestRun) {
+				m.collectLatestRun(org, repo, &settings, workflows, workflowRuns, ownerLabels, callback)
+			}
+
+			if settings.IsCollectorEnabled(config.CollectorLastRun) {
+				m.collectLastRun(org, repo, &settings, workflows, workflowRuns, callback)
+			}
+
+			// runs of all branches (eg. dependency update pull requests)
+			if settings.IsCollectorEnabled(config.CollectorActors) {
+				allBranchesWorkflowRuns, err := m.getRepoAllBranchesWorkflowRuns(org, repo, &settings)
+				if err != nil {
+					m.Logger().Warn(`unable to fetch workflow runs of all branches`, slog.String("repository", repo.GetName()), slog.Any("error", err))
+				} else {
+					m.collectActorRuns(org.Name, repo, &settings, workflows, allBranchesWorkflowRuns, callback)
+				}
+			}
+
+			if settings.IsCollectorEnabled(config.CollectorStuck) {
+				m.collectStuckRuns(org, repo, &settings, workflows, workflowRuns, ownerLabels, callback)
 			}
+
+			if settings.IsCollectorEnabled(config.CollectorAwaitingApproval) {
+				m.collectAwaitingApprovalRuns(org, repo, workflows, ownerLabels, callback)
+			}
+		}
+
+		if settings.IsCollectorEnabled(config.CollectorDeployments) {
+			m.collectDeployments(org, repo, &settings, workflowRuns, callback)
 		}
 	}
 }
 
-func (m *MetricsCollectorGithubWorkflows) collectRunningRuns(org string, repo *github.Repository, workflows map[int64]*github.Workflow, workflowRun []*github.WorkflowRun, callback chan<- func()) {
-	runMetric := m.Collector.GetMetricList("workflowRunRunning")
-	runStartTimeMetric := m.Collector.GetMetricList("workflowRunRunningStartTime")
+func (m *MetricsCollectorGithubWorkflows) collectRunningRuns(org string, repo *github.Repository, workflows map[int64]*github.Workflow, workflowRun []*github.WorkflowRun, ownerLabels prometheus.Labels, callback chan<- func()) {
+	runMetric := m.getMetricList("workflowRunRunning")
+	runStartTimeMetric := m.getMetricList("workflowRunRunningStartTime")
 
 	for _, row := range workflowRun {
 		workflowRun := row
@@ -435,6 +1499,11 @@ func (m *MetricsCollectorGithubWorkflows) collectRunningRuns(org string, repo *g
 			continue
 		}
 
+		if githubWorkflowRunIsAwaitingApproval(workflowRun) {
+			// reported as awaiting approval
+			continue
+		}
+
 		infoLabels := prometheus.Labels{
 			"org":               org,
 			"repo":              repo.GetName(),
@@ -455,6 +1524,9 @@ func (m *MetricsCollectorGithubWorkflows) collectRunningRuns(org string, repo *g
 			infoLabels["workflow"] = workflow.GetName()
 			infoLabels["workflowUrl"] = workflow.GetHTMLURL()
 		}
+		for labelName, labelValue := range ownerLabels {
+			infoLabels[labelName] = labelValue
+		}
 
 		statLabels := prometheus.Labels{
 			"org":               org,
@@ -468,15 +1540,15 @@ func (m *MetricsCollectorGithubWorkflows) collectRunningRuns(org string, repo *g
 	}
 }
 
-func (m *MetricsCollectorGithubWorkflows) collectLatestRun(org string, repo *github.Repository, workflows map[int64]*github.Workflow, workflowRun []*github.WorkflowRun, callback chan<- func()) {
-	runMetric := m.Collector.GetMetricList("workflowLatestRun")
-	runTimestampMetric := m.Collector.GetMetricList("workflowLatestRunStartTime")
-	runDurationMetric := m.Collector.GetMetricList("workflowLatestRunDuration")
+func (m *MetricsCollectorGithubWorkflows) collectLatestRun(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings, workflows map[int64]*github.Workflow, workflowRun []*github.WorkflowRun, ownerLabels prometheus.Labels, callback chan<- func()) {
+	runMetric := m.getMetricList("workflowLatestRun")
+	runTimestampMetric := m.getMetricList("workflowLatestRunStartTime")
+	runDurationMetric := m.getMetricList("workflowLatestRunDuration")
 
-	latestJobs := map[int64]*github.WorkflowRun{}
+	latestJobs := map[string]*github.WorkflowRun{}
 	for _, row := range workflowRun {
 		workflowRun := row
-		workflowId := workflowRun.GetWorkflowID()
+		workflowKey := workflowRunKey(workflowRun)
 
 		// skip forks
 		if workflowRun.GetHeadRepository().Fork != nil && *workflowRun.GetHeadRepository().Fork {
@@ -493,16 +1565,43 @@ func (m *MetricsCollectorGithubWorkflows) collectLatestRun(org string, repo *git
 			continue
 		}
 
-		if _, exists := latestJobs[workflowId]; !exists {
-			latestJobs[workflowId] = workflowRun
-		} else if latestJobs[workflowId].GetCreatedAt().Before(workflowRun.GetCreatedAt().Time) {
-			latestJobs[workflowId] = workflowRun
+		if _, exists := latestJobs[workflowKey]; !exists {
+			latestJobs[workflowKey] = workflowRun
+		} else if latestJobs[workflowKey].GetCreatedAt().Before(workflowRun.GetCreatedAt().Time) {
+			latestJobs[workflowKey] = workflowRun
+		}
+	}
+
+	// lookup latest run of active workflows without finished runs within timeframe (eg. monthly workflows)
+	workflowsWithRuns := map[int64]bool{}
+	for _, workflowRun := range latestJobs {
+		workflowsWithRuns[workflowRun.GetWorkflowID()] = true
+
+		// latest run within timeframe replaces older cached run (still used after run left the timeframe)
+		cacheKey := workflowCacheKey(org, repo, settings, workflowRun.GetWorkflowID())
+		if cachedRun := m.latestCompletedRuns[cacheKey]; cachedRun == nil || cachedRun.GetCreatedAt().Before(workflowRun.GetCreatedAt().Time) {
+			m.latestCompletedRuns[cacheKey] = workflowRun
+		}
+	}
+	for workflowID, workflow := range workflows {
+		if workflowsWithRuns[workflowID] || workflow.GetState() != "active" {
+			continue
+		}
+
+		workflowRun, err := m.getWorkflowLatestCompletedRun(org, repo, settings, workflowID)
+		if err != nil {
+			m.Logger().Warn(`unable to fetch latest workflow run`, slog.String("repository", repo.GetName()), slog.Int64("workflowID", workflowID), slog.Any("error", err))
+			continue
+		}
+
+		if workflowRun != nil {
+			latestJobs[workflowRunKey(workflowRun)] = workflowRun
 		}
 	}
 
 	for _, workflowRun := range latestJobs {
 		infoLabels := prometheus.Labels{
-			"org":               org,
+			"org":               org.Name,
 			"repo":              repo.GetName(),
 			"workflowID":        fmt.Sprintf("%v", workflowRun.GetWorkflowID()),
 			"workflowRunNumber": fmt.Sprintf("%v", workflowRun.GetRunNumber()),
@@ -520,9 +1619,12 @@ func (m *MetricsCollectorGithubWorkflows) collectLatestRun(org string, repo *git
 			infoLabels["workflow"] = workflow.GetName()
 			infoLabels["workflowUrl"] = workflow.GetHTMLURL()
 		}
+		for labelName, labelValue := range ownerLabels {
+			infoLabels[labelName] = labelValue
+		}
 
 		statLabels := prometheus.Labels{
-			"org":               org,
+			"org":               org.Name,
 			"repo":              repo.GetName(),
 			"workflowID":        fmt.Sprintf("%v", workflowRun.GetWorkflowID()),
 			"workflowRunNumber": fmt.Sprintf("%v", workflowRun.GetRunNumber()),
@@ -534,18 +1636,22 @@ func (m *MetricsCollectorGithubWorkflows) collectLatestRun(org string, repo *git
 	}
 }
 
-func (m *MetricsCollectorGithubWorkflows) collectConsecutiveFailures(org string, repo *github.Repository, workflows map[int64]*github.Workflow, workflowRun []*github.WorkflowRun, callback chan<- func()) {
-	consecutiveFailuresMetric := m.Collector.GetMetricList("workflowConsecutiveFailures")
+func (m *MetricsCollectorGithubWorkflows) collectConsecutiveFailures(org string, repo *github.Repository, settings *config.RepositorySettings, workflows map[int64]*github.Workflow, workflowRun []*github.WorkflowRun, ownerLabels prometheus.Labels, callback chan<- func()) {
+	consecutiveFailuresMetric := m.getMetricList("workflowConsecutiveFailures")
+	consecutiveFailuresStartTimeMetric := m.getMetricList("workflowConsecutiveFailuresStartTime")
 
-	consecutiveFailMap := map[int64]*struct {
+	consecutiveFailMap := map[string]*struct {
 		count  int64
 		labels prometheus.Labels
+
+		// first (oldest) failing run of the streak
+		firstFailedRun *github.WorkflowRun
 	}{}
-	consecutiveFinishedMap := map[int64]bool{}
+	consecutiveFinishedMap := map[string]bool{}
 
 	for _, row := range workflowRun {
 		workflowRun := row
-		workflowId := workflowRun.GetWorkflowID()
+		workflowKey := workflowRunKey(workflowRun)
 
 		// ignore running/not finished workflow runs
 		switch workflowRun.GetStatus() {
@@ -561,7 +1667,7 @@ func (m *MetricsCollectorGithubWorkflows) collectConsecutiveFailures(org string,
 			continue
 		}
 
-		if _, exists := consecutiveFailMap[workflowId]; !exists {
+		if _, exists := consecutiveFailMap[workflowKey]; !exists {
 			infoLabels := prometheus.Labels{
 				"org":               org,
 				"repo":              repo.GetName(),
@@ -580,34 +1686,97 @@ func (m *MetricsCollectorGithubWorkflows) collectConsecutiveFailures(org string,
 				infoLabels["workflow"] = workflow.GetName()
 				infoLabels["workflowUrl"] = workflow.GetHTMLURL()
 			}
+			for labelName, labelValue := range ownerLabels {
+				infoLabels[labelName] = labelValue
+			}
 
-			consecutiveFailMap[workflowId] = &struct {
+			consecutiveFailMap[workflowKey] = &struct {
 				count  int64
 				labels prometheus.Labels
+
+				// first (oldest) failing run of the streak
+				firstFailedRun *github.WorkflowRun
 			}{
 				count:  0,
 				labels: infoLabels,
 			}
-			consecutiveFinishedMap[workflowId] = false
+			consecutiveFinishedMap[workflowKey] = false
 		}
 
 		// successful run found for workload id, skipping all further runs
-		if consecutiveFinishedMap[workflowId] {
+		if consecutiveFinishedMap[workflowKey] {
 			continue
 		}
 
-		switch workflowRun.GetConclusion() {
-		case "":
+		if workflowRun.GetConclusion() == "" {
 			continue
-		case "failure":
-			consecutiveFailMap[workflowId].count++
-		case "success":
-			consecutiveFinishedMap[workflowId] = true
+		}
+
+		switch settings.ConclusionClass(workflowRun.GetConclusion()) {
+		case config.ConclusionFailing:
+			consecutiveFailMap[workflowKey].count++
+			consecutiveFailMap[workflowKey].firstFailedRun = workflowRun
+		case config.ConclusionPassing:
+			consecutiveFinishedMap[workflowKey] = true
 		}
 	}
 
 	// process metrics
 	for _, row := range consecutiveFailMap {
 		consecutiveFailuresMetric.Add(row.labels, float64(row.count))
+
+		if row.firstFailedRun != nil {
+			consecutiveFailuresStartTimeMetric.AddTime(prometheus.Labels{
+				"org":               org,
+				"repo":              repo.GetName(),
+				"workflowID":        fmt.Sprintf("%v", row.firstFailedRun.GetWorkflowID()),
+				"branch":            row.firstFailedRun.GetHeadBranch(),
+				"workflowRunNumber": fmt.Sprintf("%v", row.firstFailedRun.GetRunNumber()),
+				"workflowRunUrl":    row.firstFailedRun.GetHTMLURL(),
+				"headSha":           row.firstFailedRun.GetHeadSHA(),
+				"actorLogin":        row.firstFailedRun.Actor.GetLogin(),
+				"actorType":         row.firstFailedRun.Actor.GetType(),
+			}, row.firstFailedRun.GetCreatedAt().Time)
+		}
+	}
+}
+
+// workflowRunBranchFilter returns the branch for filtering workflow runs by the API,
+// multiple branches or branch patterns can't be filtered by the API and must be filtered after fetching (filterBranches)
+func workflowRunBranchFilter(repo *github.Repository, settings *config.RepositorySettings) (branch string, filterBranches bool) {
+	switch {
+	case len(settings.Branches) == 1 && !strings.ContainsAny(settings.Branches[0], `*?[\`):
+		return settings.Branches[0], false
+	case len(settings.Branches) >= 1:
+		return "", true
+	default:
+		return repo.GetDefaultBranch(), false
+	}
+}
+
+// workflowRunKey returns the key for grouping workflow runs per workflow and branch
+func workflowRunKey(workflowRun *github.WorkflowRun) string {
+	return fmt.Sprintf("%d:%s", workflowRun.GetWorkflowID(), workflowRun.GetHeadBranch())
+}
+
+// repositoryMetadataLabel returns the label value of repository metadata
+func repositoryMetadataLabel(repo *github.Repository, labelName string) string {
+	switch labelName {
+	case "topics":
+		topics := slices.Clone(repo.Topics)
+		slices.Sort(topics)
+		return strings.Join(topics, ",")
+	case "visibility":
+		return repo.GetVisibility()
+	case "language":
+		return repo.GetLanguage()
+	case "fork":
+		return to.BoolString(repo.GetFork())
+	case "template":
+		return to.BoolString(repo.GetIsTemplate())
+	case "archived":
+		return to.BoolString(repo.GetArchived())
 	}
+
+	return ""
 }
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/github-workflow-exporter/config"
+)
+
+// collectActorRuns collects the count of workflow runs and failed workflow runs of all branches (eg. dependency update
+// pull requests) within the timeframe per workflow and actor,
+// bots are counted per login (eg. dependabot[bot]) and users only per login if enabled (otherwise aggregated per actor type)
+func (m *MetricsCollectorGithubWorkflows) collectActorRuns(org string, repo *github.Repository, settings *config.RepositorySettings, workflows map[int64]*github.Workflow, workflowRuns []*github.WorkflowRun, callback chan<- func()) {
+	runCountMetric := m.getMetricList("workflowActorRunCount")
+	failedRunCountMetric := m.getMetricList("workflowActorFailedRunCount")
+
+	type actorStats struct {
+		labels     prometheus.Labels
+		runs       int64
+		failedRuns int64
+	}
+	stats := map[string]*actorStats{}
+
+	for _, workflowRun := range workflowRuns {
+		workflow, exists := workflows[workflowRun.GetWorkflowID()]
+		if !exists {
+			continue
+		}
+
+		actorType := workflowRun.Actor.GetType()
+		actorLogin := ""
+		if actorType == "Bot" || Opts.GitHub.Workflows.Actors.PerUser {
+			actorLogin = workflowRun.Actor.GetLogin()
+		}
+
+		actorKey := fmt.Sprintf("%v:%s:%s", workflowRun.GetWorkflowID(), actorType, actorLogin)
+		if _, exists := stats[actorKey]; !exists {
+			stats[actorKey] = &actorStats{
+				labels: prometheus.Labels{
+					"org":        org,
+					"repo":       repo.GetName(),
+					"workflowID": fmt.Sprintf("%v", workflowRun.GetWorkflowID()),
+					"workflow":   workflow.GetName(),
+					"actorType":  actorType,
+					"actorLogin": actorLogin,
+				},
+			}
+		}
+		actorStat := stats[actorKey]
+
+		actorStat.runs++
+		if workflowRun.GetStatus() == "completed" && settings.ConclusionClass(workflowRun.GetConclusion()) == config.ConclusionFailing {
+			actorStat.failedRuns++
+		}
+	}
+
+	for _, actorStat := range stats {
+		runCountMetric.Add(actorStat.labels, float64(actorStat.runs))
+		failedRunCountMetric.Add(actorStat.labels, float64(actorStat.failedRuns))
+	}
+}
@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type (
+	// githubPendingDeployment is a deployment of a workflow run waiting for approval
+	// (go-github only supports reviewing pending deployments, not listing them)
+	githubPendingDeployment struct {
+		Environment struct {
+			ID   int64  `json:"id"`
+			Name string `json:"name"`
+		} `json:"environment"`
+		WaitTimer          int64             `json:"wait_timer"`
+		WaitTimerStartedAt *github.Timestamp `json:"wait_timer_started_at"`
+	}
+
+	// githubRunApproval is a review of the deployments of a workflow run
+	// (not available in go-github)
+	githubRunApproval struct {
+		State        string `json:"state"`
+		Environments []struct {
+			Name string `json:"name"`
+		} `json:"environments"`
+	}
+
+	// githubRunApprovalReview is the review result of one environment of a completed workflow run
+	githubRunApprovalReview struct {
+		workflowID  int64
+		environment string
+		state       string
+
+		// time from waiting for review until deployment continued or was rejected, nil if unknown
+		latency *time.Duration
+	}
+)
+
+// getRunPendingDeployments fetches the deployments of a workflow run waiting for approval
+func (m *MetricsCollectorGithubWorkflows) getRunPendingDeployments(org *GithubOrganization, repo string, runID int64) ([]*githubPendingDeployment, error) {
+	var pendingDeployments []*githubPendingDeployment
+
+	for {
+		m.Logger().Debug(`fetching pending deployments`, slog.String("repository", repo), slog.Int64("runID", runID))
+
+		req, err := org.Client.NewRequest("GET", fmt.Sprintf("repos/%v/%v/actions/runs/%v/pending_deployments", org.Name, repo, runID), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = org.Client.Do(m.Context(), req, &pendingDeployments)
+		var ghRateLimitError *github.RateLimitError
+		if ok := errors.As(err, &ghRateLimitError); ok {
+			m.Logger().Debug("request GetPendingDeployments rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+			time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	return pendingDeployments, nil
+}
+
+// getRunApprovalReviews fetches the approval reviews of a completed workflow run,
+// the latency is looked up per environment using the deployment statuses of the environment
+func (m *MetricsCollectorGithubWorkflows) getRunApprovalReviews(org *GithubOrganization, repo string, workflowRun *github.WorkflowRun) ([]*githubRunApprovalReview, error) {
+	var approvals []*githubRunApproval
+	for {
+		m.Logger().Debug(`fetching run approvals`, slog.String("repository", repo), slog.Int64("runID", workflowRun.GetID()))
+
+		req, err := org.Client.NewRequest("GET", fmt.Sprintf("repos/%v/%v/actions/runs/%v/approvals", org.Name, repo, workflowRun.GetID()), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = org.Client.Do(m.Context(), req, &approvals)
+		var ghRateLimitError *github.RateLimitError
+		if ok := errors.As(err, &ghRateLimitError); ok {
+			m.Logger().Debug("request GetRunApprovals rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+			time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	reviews := []*githubRunApprovalReview{}
+	for _, approval := range approvals {
+		for _, environment := range approval.Environments {
+			latency, err := m.getRunEnvironmentApprovalLatency(org, repo, workflowRun, environment.Name)
+			if err != nil {
+				return nil, err
+			}
+
+			reviews = append(reviews, &githubRunApprovalReview{
+				workflowID:  workflowRun.GetWorkflowID(),
+				environment: environment.Name,
+				state:       approval.State,
+				latency:     latency,
+			})
+		}
+	}
+
+	return reviews, nil
+}
+
+// getRunEnvironmentApprovalLatency returns the time the deployment of the workflow run to the environment was waiting
+// for the review (from waiting status until next status: queued/in_progress if approved, failure if rejected),
+// nil if no reviewed deployment is found
+func (m *MetricsCollectorGithubWorkflows) getRunEnvironmentApprovalLatency(org *GithubOrganization, repo string, workflowRun *github.WorkflowRun, environment string) (*time.Duration, error) {
+	var deployments []*github.Deployment
+	for {
+		m.Logger().Debug(`fetching run deployments`, slog.String("repository", repo), slog.Int64("runID", workflowRun.GetID()), slog.String("environment", environment))
+
+		var err error
+		deployments, _, err = org.Client.Repositories.ListDeployments(m.Context(), org.Name, repo, &github.DeploymentsListOptions{
+			SHA:         workflowRun.GetHeadSHA(),
+			Environment: environment,
+			ListOptions: github.ListOptions{PerPage: 100},
+		})
+		var ghRateLimitError *github.RateLimitError
+		if ok := errors.As(err, &ghRateLimitError); ok {
+			m.Logger().Debug("request ListDeployments rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+			time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	for _, deployment := range deployments {
+		// only deployments created by the (latest attempt of the) workflow run
+		if deployment.GetCreatedAt().Before(workflowRun.GetRunStartedAt().Time) || deployment.GetCreatedAt().After(workflowRun.GetUpdatedAt().Time) {
+			continue
+		}
+
+		statuses, err := m.getDeploymentStatuses(org, repo, deployment.GetID())
+		if err != nil {
+			return nil, err
+		}
+
+		// statuses are sorted by creation time (newest first)
+		for i := len(statuses) - 1; i >= 1; i-- {
+			if statuses[i].GetState() != "waiting" {
+				continue
+			}
+
+			switch nextStatus := statuses[i-1]; nextStatus.GetState() {
+			case "queued", "in_progress", "failure":
+				latency := nextStatus.GetCreatedAt().Sub(statuses[i].GetCreatedAt().Time)
+				return &latency, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+// collectPendingDeployments collects the deployments of waiting workflow runs which are waiting for approval
+func (m *MetricsCollectorGithubWorkflows) collectPendingDeployments(org *GithubOrganization, repo *github.Repository, workflows map[int64]*github.Workflow, workflowRuns []*github.WorkflowRun, ownerLabels prometheus.Labels, callback chan<- func()) {
+	pendingDeploymentMetric := m.getMetricList("workflowRunPendingDeployment")
+	pendingDeploymentWaitMetric := m.getMetricList("workflowRunPendingDeploymentWait")
+	pendingDeploymentCountMetric := m.getMetricList("deploymentPendingCount")
+
+	pendingCount := map[string]int64{}
+
+	for _, workflowRun := range workflowRuns {
+		if workflowRun.GetStatus() != "waiting" {
+			continue
+		}
+
+		pendingDeployments, err := m.getRunPendingDeployments(org, repo.GetName(), workflowRun.GetID())
+		if err != nil {
+			m.Logger().Warn(`unable to fetch pending deployments`, slog.String("repository", repo.GetName()), slog.Int64("runID", workflowRun.GetID()), slog.Any("error", err))
+			continue
+		}
+
+		for _, pendingDeployment := range pendingDeployments {
+			environment := pendingDeployment.Environment.Name
+			pendingCount[environment]++
+
+			infoLabels := prometheus.Labels{
+				"org":               org.Name,
+				"repo":              repo.GetName(),
+				"workflowID":        fmt.Sprintf("%v", workflowRun.GetWorkflowID()),
+				"workflowRunNumber": fmt.Sprintf("%v", workflowRun.GetRunNumber()),
+				"workflow":          LABEL_VALUE_UNKNOWN,
+				"workflowRunUrl":    workflowRun.GetHTMLURL(),
+				"branch":            workflowRun.GetHeadBranch(),
+				"environment":       environment,
+			}
+			if workflow, ok := workflows[workflowRun.GetWorkflowID()]; ok {
+				infoLabels["workflow"] = workflow.GetName()
+			}
+			for labelName, labelValue := range ownerLabels {
+				infoLabels[labelName] = labelValue
+			}
+
+			statLabels := prometheus.Labels{
+				"org":               org.Name,
+				"repo":              repo.GetName(),
+				"workflowID":        fmt.Sprintf("%v", workflowRun.GetWorkflowID()),
+				"workflowRunNumber": fmt.Sprintf("%v", workflowRun.GetRunNumber()),
+				"environment":       environment,
+			}
+
+			// waiting since the wait timer started, otherwise since the run changed to waiting
+			waitingSince := workflowRun.GetUpdatedAt().Time
+			if pendingDeployment.WaitTimerStartedAt != nil {
+				waitingSince = pendingDeployment.WaitTimerStartedAt.Time
+			}
+
+			pendingDeploymentMetric.AddInfo(infoLabels)
+			pendingDeploymentWaitMetric.Add(statLabels, time.Since(waitingSince).Seconds())
+		}
+	}
+
+	for environment, count := range pendingCount {
+		pendingDeploymentCountMetric.Add(prometheus.Labels{
+			"org":         org.Name,
+			"repo":        repo.GetName(),
+			"environment": environment,
+		}, float64(count))
+	}
+}
+
+// collectApprovals collects the approval/rejection latency of completed workflow runs,
+// reviews of completed runs don't change and are only fetched once
+func (m *MetricsCollectorGithubWorkflows) collectApprovals(org *GithubOrganization, repo *github.Repository, workflows map[int64]*github.Workflow, workflowRuns []*github.WorkflowRun, callback chan<- func()) {
+	approvalCountMetric := m.getMetricList("workflowApprovalCount")
+	approvalLatencyMetric := m.getMetricList("workflowApprovalLatency")
+
+	cacheKey := fmt.Sprintf("%s/%s", org.Name, repo.GetName())
+	cachedReviews := m.approvalReviews[cacheKey]
+
+	// only keep reviews of runs within the timeframe
+	reviewsByRun := map[int64][]*githubRunApprovalReview{}
+	for _, workflowRun := range workflowRuns {
+		if workflowRun.GetStatus() != "completed" {
+			continue
+		}
+
+		if reviews, exists := cachedReviews[workflowRun.GetID()]; exists {
+			reviewsByRun[workflowRun.GetID()] = reviews
+			continue
+		}
+
+		reviews, err := m.getRunApprovalReviews(org, repo.GetName(), workflowRun)
+		if err != nil {
+			m.Logger().Warn(`unable to fetch run approvals`, slog.String("repository", repo.GetName()), slog.Int64("runID", workflowRun.GetID()), slog.Any("error", err))
+			continue
+		}
+		reviewsByRun[workflowRun.GetID()] = reviews
+	}
+	m.approvalReviews[cacheKey] = reviewsByRun
+
+	type approvalStats struct {
+		labels       prometheus.Labels
+		count        int64
+		latencyCount int64
+		latencySum   time.Duration
+	}
+	stats := map[string]*approvalStats{}
+
+	for _, reviews := range reviewsByRun {
+		for _, review := range reviews {
+			statsKey := fmt.Sprintf("%d:%s:%s", review.workflowID, review.environment, review.state)
+			if _, exists := stats[statsKey]; !exists {
+				labels := prometheus.Labels{
+					"org":         org.Name,
+					"repo":        repo.GetName(),
+					"workflowID":  fmt.Sprintf("%v", review.workflowID),
+					"workflow":    LABEL_VALUE_UNKNOWN,
+					"environment": review.environment,
+					"state":       review.state,
+				}
+				if workflow, ok := workflows[review.workflowID]; ok {
+					labels["workflow"] = workflow.GetName()
+				}
+				stats[statsKey] = &approvalStats{labels: labels}
+			}
+
+			stats[statsKey].count++
+			if review.latency != nil {
+				stats[statsKey].latencyCount++
+				stats[statsKey].latencySum += *review.latency
+			}
+		}
+	}
+
+	for _, row := range stats {
+		approvalCountMetric.Add(row.labels, float64(row.count))
+		if row.latencyCount >= 1 {
+			approvalLatencyMetric.Add(row.labels, row.latencySum.Seconds()/float64(row.latencyCount))
+		}
+	}
+}
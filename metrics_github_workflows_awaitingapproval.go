@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// getRepoAwaitingApprovalRuns fetches all workflow runs awaiting approval of a repository, without branch filter and timeframe
+// as runs of pull requests from forks are running on the branch of the fork and are waiting until approved
+func (m *MetricsCollectorGithubWorkflows) getRepoAwaitingApprovalRuns(org *GithubOrganization, repo *github.Repository) ([]*github.WorkflowRun, error) {
+	var workflowRuns []*github.WorkflowRun
+
+	opts := github.ListWorkflowRunsOptions{
+		Status:              githubWorkflowAwaitingApprovalStatus,
+		ExcludePullRequests: true,
+		ListOptions:         github.ListOptions{PerPage: 100, Page: 1},
+	}
+
+	for {
+		m.Logger().Debug(`fetching list of workflow runs awaiting approval for repository`, slog.String("repository", repo.GetName()), slog.Int("page", opts.Page))
+
+		result, response, err := org.Client.Actions.ListRepositoryWorkflowRuns(m.Context(), org.Name, repo.GetName(), &opts)
+		var ghRateLimitError *github.RateLimitError
+		if ok := errors.As(err, &ghRateLimitError); ok {
+			m.Logger().Debug("request ListRepositoryWorkflowRuns rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+			time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+			continue
+		} else if err != nil {
+			return workflowRuns, err
+		}
+
+		workflowRuns = append(workflowRuns, result.WorkflowRuns...)
+
+		// calc next page
+		if response.NextPage == 0 {
+			break
+		}
+		opts.Page = response.NextPage
+	}
+
+	return workflowRuns, nil
+}
+
+// collectAwaitingApprovalRuns collects workflow runs awaiting approval of a maintainer (eg. pull requests from first-time
+// contributors) of all branches
+func (m *MetricsCollectorGithubWorkflows) collectAwaitingApprovalRuns(org *GithubOrganization, repo *github.Repository, workflows map[int64]*github.Workflow, ownerLabels prometheus.Labels, callback chan<- func()) {
+	runMetric := m.getMetricList("workflowRunAwaitingApproval")
+	runAgeMetric := m.getMetricList("workflowRunAwaitingApprovalAge")
+
+	awaitingApprovalRuns, err := m.getRepoAwaitingApprovalRuns(org, repo)
+	if err != nil {
+		m.Logger().Warn(`unable to fetch workflow runs awaiting approval`, slog.String("repository", repo.GetName()), slog.Any("error", err))
+	}
+
+	for _, workflowRun := range awaitingApprovalRuns {
+		if !githubWorkflowRunIsAwaitingApproval(workflowRun) {
+			continue
+		}
+
+		infoLabels := prometheus.Labels{
+			"org":               org.Name,
+			"repo":              repo.GetName(),
+			"workflowID":        fmt.Sprintf("%v", workflowRun.GetWorkflowID()),
+			"workflowRunNumber": fmt.Sprintf("%v", workflowRun.GetRunNumber()),
+			"workflow":          LABEL_VALUE_UNKNOWN,
+			"workflowRunUrl":    workflowRun.GetHTMLURL(),
+			"event":             workflowRun.GetEvent(),
+			"branch":            workflowRun.GetHeadBranch(),
+			"actorLogin":        workflowRun.Actor.GetLogin(),
+			"actorType":         workflowRun.Actor.GetType(),
+		}
+		if workflow, ok := workflows[workflowRun.GetWorkflowID()]; ok {
+			infoLabels["workflow"] = workflow.GetName()
+		}
+		for labelName, labelValue := range ownerLabels {
+			infoLabels[labelName] = labelValue
+		}
+
+		statLabels := prometheus.Labels{
+			"org":               org.Name,
+			"repo":              repo.GetName(),
+			"workflowID":        fmt.Sprintf("%v", workflowRun.GetWorkflowID()),
+			"workflowRunNumber": fmt.Sprintf("%v", workflowRun.GetRunNumber()),
+		}
+
+		runMetric.AddInfo(infoLabels)
+		runAgeMetric.Add(statLabels, time.Since(workflowRun.GetCreatedAt().Time).Seconds())
+	}
+}
+
+// githubWorkflowRunIsAwaitingApproval returns true if workflow run is waiting for approval of a maintainer
+func githubWorkflowRunIsAwaitingApproval(workflowRun *github.WorkflowRun) bool {
+	return workflowRun.GetStatus() == githubWorkflowAwaitingApprovalStatus ||
+		(workflowRun.GetStatus() == "completed" && workflowRun.GetConclusion() == githubWorkflowAwaitingApprovalStatus)
+}
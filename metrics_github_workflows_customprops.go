@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+)
+
+type (
+	// githubRepoCustomPropertyValues is the same as github.RepoCustomPropertyValue,
+	// but values are kept raw as multi-select properties are returned as list of strings
+	githubRepoCustomPropertyValues struct {
+		RepositoryName string `json:"repository_name"`
+		Properties     []struct {
+			PropertyName string          `json:"property_name"`
+			Value        json.RawMessage `json:"value"`
+		} `json:"properties"`
+	}
+)
+
+// getOrgCustomPropertyValues fetches the custom property values of all repositories of the organization (repository name as key)
+func (m *MetricsCollectorGithubWorkflows) getOrgCustomPropertyValues(org *GithubOrganization) (map[string]map[string]string, error) {
+	customProperties := map[string]map[string]string{}
+
+	opts := github.ListOptions{PerPage: 100, Page: 1}
+
+	for {
+		m.Logger().Debug(`fetching custom property values`, slog.String("org", org.Name), slog.Int("page", opts.Page))
+
+		// same as Organizations.ListCustomPropertyValues, which fails for multi-select properties
+		req, err := org.Client.NewRequest("GET", fmt.Sprintf("orgs/%v/properties/values?per_page=%d&page=%d", org.Name, opts.PerPage, opts.Page), nil)
+		if err != nil {
+			return nil, err
+		}
+
+		var result []*githubRepoCustomPropertyValues
+		response, err := org.Client.Do(m.Context(), req, &result)
+		var ghRateLimitError *github.RateLimitError
+		if ok := errors.As(err, &ghRateLimitError); ok {
+			m.Logger().Debug("request ListCustomPropertyValues rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+			time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		for _, repoValues := range result {
+			values := map[string]string{}
+			for _, property := range repoValues.Properties {
+				values[property.PropertyName] = customPropertyValue(property.Value)
+			}
+			customProperties[repoValues.RepositoryName] = values
+		}
+
+		// calc next page
+		if response.NextPage == 0 {
+			break
+		}
+		opts.Page = response.NextPage
+	}
+
+	return customProperties, nil
+}
+
+// customPropertyValue returns the label value of a custom property value (multi-select values are comma separated)
+func customPropertyValue(value json.RawMessage) string {
+	var stringValue string
+	if err := json.Unmarshal(value, &stringValue); err == nil {
+		return stringValue
+	}
+
+	var listValue []string
+	if err := json.Unmarshal(value, &listValue); err == nil {
+		return strings.Join(listValue, ",")
+	}
+
+	return ""
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/github-workflow-exporter/config"
+)
+
+const (
+	DEPLOYMENT_STATE_SUCCESS = "success"
+	DEPLOYMENT_STATE_FAILURE = "failure"
+)
+
+type (
+	// githubDeployment is a deployment with its final state
+	githubDeployment struct {
+		deployment *github.Deployment
+
+		// success or failure, empty if deployment is not finished
+		state      string
+		finishedAt time.Time
+	}
+)
+
+// getRepoDeployments fetches all deployments of the repository created within the timeframe (incl. their final state),
+// deployments with failed state requests are skipped
+func (m *MetricsCollectorGithubWorkflows) getRepoDeployments(org *GithubOrganization, repo string, settings *config.RepositorySettings) ([]*githubDeployment, error) {
+	var deployments []*githubDeployment
+
+	createdSince := time.Now().Add(-settings.Timeframe)
+
+	opts := github.DeploymentsListOptions{
+		ListOptions: github.ListOptions{PerPage: 100, Page: 1},
+	}
+
+	for {
+		m.Logger().Debug(`fetching deployments`, slog.String("repository", repo), slog.Int("page", opts.Page))
+
+		result, response, err := org.Client.Repositories.ListDeployments(m.Context(), org.Name, repo, &opts)
+		var ghRateLimitError *github.RateLimitError
+		if ok := errors.As(err, &ghRateLimitError); ok {
+			m.Logger().Debug("request ListDeployments rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+			time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		// deployments are sorted by creation time (newest first)
+		reachedTimeframe := false
+		for _, deployment := range result {
+			if deployment.GetCreatedAt().Before(createdSince) {
+				reachedTimeframe = true
+				break
+			}
+
+			deployments = append(deployments, &githubDeployment{deployment: deployment})
+		}
+
+		// calc next page
+		if reachedTimeframe || response.NextPage == 0 {
+			break
+		}
+		opts.Page = response.NextPage
+	}
+
+	// final states of deployments don't change and are only fetched once (only deployments within timeframe are kept)
+	cacheKey := fmt.Sprintf("%s/%s", org.Name, repo)
+	cachedDeployments := m.finishedDeployments[cacheKey]
+	finishedDeployments := map[int64]*githubDeployment{}
+
+	ret := make([]*githubDeployment, 0, len(deployments))
+	for _, deployment := range deployments {
+		if cachedDeployment, exists := cachedDeployments[deployment.deployment.GetID()]; exists {
+			deployment.state = cachedDeployment.state
+			deployment.finishedAt = cachedDeployment.finishedAt
+		} else if err := m.fetchDeploymentState(org, repo, deployment); err != nil {
+			m.Logger().Warn(`unable to fetch deployment state, skipping deployment`, slog.String("repository", repo), slog.Int64("deploymentID", deployment.deployment.GetID()), slog.Any("error", err))
+			continue
+		}
+
+		if deployment.state != "" {
+			finishedDeployments[deployment.deployment.GetID()] = deployment
+		}
+		ret = append(ret, deployment)
+	}
+	m.finishedDeployments[cacheKey] = finishedDeployments
+
+	return ret, nil
+}
+
+// getDeploymentStatuses fetches the statuses of a deployment (newest first)
+func (m *MetricsCollectorGithubWorkflows) getDeploymentStatuses(org *GithubOrganization, repo string, deploymentID int64) ([]*github.DeploymentStatus, error) {
+	for {
+		m.Logger().Debug(`fetching deployment statuses`, slog.String("repository", repo), slog.Int64("deploymentID", deploymentID))
+
+		statuses, _, err := org.Client.Repositories.ListDeploymentStatuses(m.Context(), org.Name, repo, deploymentID, &github.ListOptions{PerPage: 100})
+		var ghRateLimitError *github.RateLimitError
+		if ok := errors.As(err, &ghRateLimitError); ok {
+			m.Logger().Debug("request ListDeploymentStatuses rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+			time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		return statuses, nil
+	}
+}
+
+// fetchDeploymentState sets the final state of the deployment using its latest deployment status
+func (m *MetricsCollectorGithubWorkflows) fetchDeploymentState(org *GithubOrganization, repo string, deployment *githubDeployment) error {
+	statuses, err := m.getDeploymentStatuses(org, repo, deployment.deployment.GetID())
+	if err != nil {
+		return err
+	}
+
+	// statuses are sorted by creation time (newest first)
+	for _, status := range statuses {
+		switch status.GetState() {
+		case "success":
+			deployment.state = DEPLOYMENT_STATE_SUCCESS
+		case "failure", "error":
+			deployment.state = DEPLOYMENT_STATE_FAILURE
+		case "inactive":
+			// deployment was replaced by a newer one, final state is found in the older statuses
+			continue
+		}
+
+		if deployment.state != "" {
+			deployment.finishedAt = status.GetCreatedAt().Time
+		}
+		break
+	}
+
+	return nil
+}
+
+// collectDeployments calculates the DORA metrics (deployment frequency, lead time for changes,
+// change failure rate and time to restore) per environment from the deployments within the timeframe
+func (m *MetricsCollectorGithubWorkflows) collectDeployments(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings, workflowRuns []*github.WorkflowRun, callback chan<- func()) {
+	deploymentCountMetric := m.getMetricList("deploymentCount")
+	deploymentFrequencyMetric := m.getMetricList("doraDeploymentFrequency")
+	leadTimeMetric := m.getMetricList("doraLeadTime")
+	changeFailureRateMetric := m.getMetricList("doraChangeFailureRate")
+	timeToRestoreMetric := m.getMetricList("doraTimeToRestore")
+
+	deployments, err := m.getRepoDeployments(org, repo.GetName(), settings)
+	if err != nil {
+		m.Logger().Warn(`unable to fetch deployments`, slog.String("repository", repo.GetName()), slog.Any("error", err))
+		return
+	}
+
+	// commit time of the workflow runs, deployments are correlated by commit sha
+	commitTimes := map[string]time.Time{}
+	for _, workflowRun := range workflowRuns {
+		if workflowRun.GetHeadCommit().GetTimestamp().IsZero() {
+			continue
+		}
+		commitTimes[workflowRun.GetHeadSHA()] = workflowRun.GetHeadCommit().GetTimestamp().Time
+	}
+
+	environmentDeployments := map[string][]*githubDeployment{}
+	for _, deployment := range deployments {
+		environment := deployment.deployment.GetEnvironment()
+		environmentDeployments[environment] = append(environmentDeployments[environment], deployment)
+	}
+
+	timeframeDays := settings.Timeframe.Hours() / 24
+
+	for environment, deployments := range environmentDeployments {
+		// oldest first
+		sort.Slice(deployments, func(i, j int) bool {
+			return deployments[i].deployment.GetCreatedAt().Before(deployments[j].deployment.GetCreatedAt().Time)
+		})
+
+		var successCount, failureCount int64
+		var leadTimeSum, restoreTimeSum time.Duration
+		var leadTimeCount, restoreCount int64
+		var failedSince *time.Time
+
+		for _, deployment := range deployments {
+			switch deployment.state {
+			case DEPLOYMENT_STATE_SUCCESS:
+				successCount++
+
+				if commitTime, exists := commitTimes[deployment.deployment.GetSHA()]; exists && deployment.finishedAt.After(commitTime) {
+					leadTimeSum += deployment.finishedAt.Sub(commitTime)
+					leadTimeCount++
+				}
+
+				if failedSince != nil {
+					restoreTimeSum += deployment.finishedAt.Sub(*failedSince)
+					restoreCount++
+					failedSince = nil
+				}
+			case DEPLOYMENT_STATE_FAILURE:
+				failureCount++
+
+				if failedSince == nil {
+					failedSince = &deployment.finishedAt
+				}
+			}
+		}
+
+		labels := prometheus.Labels{
+			"org":         org.Name,
+			"repo":        repo.GetName(),
+			"environment": environment,
+		}
+
+		deploymentCountMetric.Add(mergeLabels(labels, prometheus.Labels{"state": DEPLOYMENT_STATE_SUCCESS}), float64(successCount))
+		deploymentCountMetric.Add(mergeLabels(labels, prometheus.Labels{"state": DEPLOYMENT_STATE_FAILURE}), float64(failureCount))
+
+		if timeframeDays > 0 {
+			deploymentFrequencyMetric.Add(labels, float64(successCount)/timeframeDays)
+		}
+
+		if leadTimeCount >= 1 {
+			leadTimeMetric.Add(labels, leadTimeSum.Seconds()/float64(leadTimeCount))
+		}
+
+		if successCount+failureCount >= 1 {
+			changeFailureRateMetric.Add(labels, float64(failureCount)/float64(successCount+failureCount))
+		}
+
+		if restoreCount >= 1 {
+			timeToRestoreMetric.Add(labels, restoreTimeSum.Seconds()/float64(restoreCount))
+		}
+	}
+}
+
+// mergeLabels returns a new label set with labels of all label sets (later label sets override earlier ones)
+func mergeLabels(labelSets ...prometheus.Labels) prometheus.Labels {
+	ret := prometheus.Labels{}
+	for _, labels := range labelSets {
+		for labelName, labelValue := range labels {
+			ret[labelName] = labelValue
+		}
+	}
+	return ret
+}
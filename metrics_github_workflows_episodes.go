@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/github-workflow-exporter/config"
+)
+
+// collectFailureEpisodes collects failure episodes (first failed run until next passing run) per workflow and branch,
+// the age of open episodes and the durations of closed episodes within the timeframe (time to fix)
+func (m *MetricsCollectorGithubWorkflows) collectFailureEpisodes(org string, repo *github.Repository, settings *config.RepositorySettings, workflows map[int64]*github.Workflow, workflowRuns []*github.WorkflowRun, callback chan<- func()) {
+	episodeOpenMetric := m.getMetricList("workflowFailureEpisodeOpen")
+	episodeDurationMetric := m.getMetricList("workflowFailureEpisodeDuration")
+
+	type failureEpisode struct {
+		labels prometheus.Labels
+
+		// finish time of first failed run, nil if there is no open episode
+		failedSince *time.Time
+	}
+	episodes := map[string]*failureEpisode{}
+
+	// workflow runs are sorted newest first, episodes are calculated from oldest to newest run
+	for i := len(workflowRuns) - 1; i >= 0; i-- {
+		workflowRun := workflowRuns[i]
+		workflowKey := workflowRunKey(workflowRun)
+
+		if workflowRun.GetStatus() != "completed" {
+			continue
+		}
+
+		if _, exists := episodes[workflowKey]; !exists {
+			labels := prometheus.Labels{
+				"org":        org,
+				"repo":       repo.GetName(),
+				"workflowID": fmt.Sprintf("%v", workflowRun.GetWorkflowID()),
+				"workflow":   LABEL_VALUE_UNKNOWN,
+				"branch":     workflowRun.GetHeadBranch(),
+			}
+			if workflow, ok := workflows[workflowRun.GetWorkflowID()]; ok {
+				labels["workflow"] = workflow.GetName()
+			}
+
+			episodes[workflowKey] = &failureEpisode{labels: labels}
+		}
+		episode := episodes[workflowKey]
+
+		finishedAt := workflowRun.GetUpdatedAt().Time
+		switch settings.ConclusionClass(workflowRun.GetConclusion()) {
+		case config.ConclusionFailing:
+			if episode.failedSince == nil {
+				episode.failedSince = &finishedAt
+			}
+		case config.ConclusionPassing:
+			if episode.failedSince != nil {
+				episodeDurationMetric.Add(episode.labels, finishedAt.Sub(*episode.failedSince).Seconds())
+				episode.failedSince = nil
+			}
+		}
+	}
+
+	for _, episode := range episodes {
+		if episode.failedSince != nil {
+			episodeOpenMetric.Add(episode.labels, time.Since(*episode.failedSince).Seconds())
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// collectEventRuns collects the count of workflow runs and the durations of completed workflow runs within the timeframe
+// per workflow and trigger event
+func (m *MetricsCollectorGithubWorkflows) collectEventRuns(org string, repo *github.Repository, workflows map[int64]*github.Workflow, workflowRuns []*github.WorkflowRun, callback chan<- func()) {
+	runCountMetric := m.getMetricList("workflowEventRunCount")
+	runDurationMetric := m.getMetricList("workflowEventRunDuration")
+
+	runCount := map[string]int64{}
+	runLabels := map[string]prometheus.Labels{}
+	for _, workflowRun := range workflowRuns {
+		workflow, exists := workflows[workflowRun.GetWorkflowID()]
+		if !exists {
+			continue
+		}
+
+		labels := prometheus.Labels{
+			"org":        org,
+			"repo":       repo.GetName(),
+			"workflowID": fmt.Sprintf("%v", workflowRun.GetWorkflowID()),
+			"workflow":   workflow.GetName(),
+			"event":      workflowRun.GetEvent(),
+		}
+
+		eventKey := fmt.Sprintf("%v:%s", workflowRun.GetWorkflowID(), workflowRun.GetEvent())
+		runCount[eventKey]++
+		runLabels[eventKey] = labels
+
+		if workflowRun.GetStatus() == "completed" {
+			duration := workflowRun.GetUpdatedAt().Sub(workflowRun.GetRunStartedAt().Time)
+			if duration > 0 {
+				runDurationMetric.Add(labels, duration.Seconds())
+			}
+		}
+	}
+
+	for eventKey, count := range runCount {
+		runCountMetric.Add(runLabels[eventKey], float64(count))
+	}
+}
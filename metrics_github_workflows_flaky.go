@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/github-workflow-exporter/config"
+)
+
+type (
+	// githubRunAttempt is the result of a previous attempt of a workflow run
+	githubRunAttempt struct {
+		conclusion string
+		startedAt  time.Time
+	}
+)
+
+// getRunAttempt fetches a previous attempt of a workflow run
+func (m *MetricsCollectorGithubWorkflows) getRunAttempt(org *GithubOrganization, repo string, runID int64, attemptNumber int) (*githubRunAttempt, error) {
+	for {
+		m.Logger().Debug(`fetching workflow run attempt`, slog.String("repository", repo), slog.Int64("runID", runID), slog.Int("attempt", attemptNumber))
+
+		workflowRun, _, err := org.Client.Actions.GetWorkflowRunAttempt(m.Context(), org.Name, repo, runID, attemptNumber, &github.WorkflowRunAttemptOptions{ExcludePullRequests: github.Bool(true)})
+		var ghRateLimitError *github.RateLimitError
+		if ok := errors.As(err, &ghRateLimitError); ok {
+			m.Logger().Debug("request GetWorkflowRunAttempt rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+			time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		return &githubRunAttempt{
+			conclusion: workflowRun.GetConclusion(),
+			startedAt:  workflowRun.GetRunStartedAt().Time,
+		}, nil
+	}
+}
+
+// collectFlakyRuns collects re-runs and runs which failed and passed afterwards on the same commit sha per workflow,
+// previous attempts are only fetched for re-run workflow runs (and only once as they don't change anymore)
+func (m *MetricsCollectorGithubWorkflows) collectFlakyRuns(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings, workflows map[int64]*github.Workflow, workflowRuns []*github.WorkflowRun, callback chan<- func()) {
+	rerunMetric := m.getMetricList("workflowReruns")
+	flakyMetric := m.getMetricList("workflowFlaky")
+	flakinessRatioMetric := m.getMetricList("workflowFlakinessRatio")
+
+	cacheKey := fmt.Sprintf("%s/%s", org.Name, repo.GetName())
+	cachedAttempts := m.runAttempts[cacheKey]
+	runAttempts := map[string]*githubRunAttempt{}
+
+	type workflowStats struct {
+		reruns int64
+
+		// results of all runs and attempts per commit sha
+		attempts map[string][]*githubRunAttempt
+	}
+	stats := map[int64]*workflowStats{}
+	for workflowID := range workflows {
+		stats[workflowID] = &workflowStats{attempts: map[string][]*githubRunAttempt{}}
+	}
+
+	for _, workflowRun := range workflowRuns {
+		workflowStat, exists := stats[workflowRun.GetWorkflowID()]
+		if !exists || workflowRun.GetStatus() != "completed" {
+			continue
+		}
+
+		headSha := workflowRun.GetHeadSHA()
+		workflowStat.attempts[headSha] = append(workflowStat.attempts[headSha], &githubRunAttempt{
+			conclusion: workflowRun.GetConclusion(),
+			startedAt:  workflowRun.GetRunStartedAt().Time,
+		})
+
+		// previous attempts of re-run workflow runs
+		for attemptNumber := 1; attemptNumber < workflowRun.GetRunAttempt(); attemptNumber++ {
+			workflowStat.reruns++
+
+			attemptKey := fmt.Sprintf("%d:%d", workflowRun.GetID(), attemptNumber)
+			attempt, exists := cachedAttempts[attemptKey]
+			if !exists {
+				var err error
+				attempt, err = m.getRunAttempt(org, repo.GetName(), workflowRun.GetID(), attemptNumber)
+				if err != nil {
+					m.Logger().Warn(`unable to fetch workflow run attempt`, slog.String("repository", repo.GetName()), slog.Int64("runID", workflowRun.GetID()), slog.Int("attempt", attemptNumber), slog.Any("error", err))
+					continue
+				}
+			}
+			runAttempts[attemptKey] = attempt
+
+			workflowStat.attempts[headSha] = append(workflowStat.attempts[headSha], attempt)
+		}
+	}
+	m.runAttempts[cacheKey] = runAttempts
+
+	for workflowID, workflowStat := range stats {
+		var flakyCount, commitCount int64
+
+		for _, attempts := range workflowStat.attempts {
+			sort.Slice(attempts, func(i, j int) bool {
+				return attempts[i].startedAt.Before(attempts[j].startedAt)
+			})
+
+			failed := false
+			flaky := false
+			finished := false
+			for _, attempt := range attempts {
+				switch settings.ConclusionClass(attempt.conclusion) {
+				case config.ConclusionFailing:
+					failed = true
+					finished = true
+				case config.ConclusionPassing:
+					flaky = flaky || failed
+					finished = true
+				}
+			}
+
+			if finished {
+				commitCount++
+			}
+			if flaky {
+				flakyCount++
+			}
+		}
+
+		labels := prometheus.Labels{
+			"org":        org.Name,
+			"repo":       repo.GetName(),
+			"workflowID": fmt.Sprintf("%v", workflowID),
+			"workflow":   workflows[workflowID].GetName(),
+		}
+
+		rerunMetric.Add(labels, float64(workflowStat.reruns))
+		flakyMetric.Add(labels, float64(flakyCount))
+		if commitCount >= 1 {
+			flakinessRatioMetric.Add(labels, float64(flakyCount)/float64(commitCount))
+		}
+	}
+}
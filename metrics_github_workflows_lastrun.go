@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/webdevops/github-workflow-exporter/config"
+)
+
+type (
+	// githubWorkflowLastRun is the latest (successful) run of a workflow, also outside of the timeframe
+	githubWorkflowLastRun struct {
+		lastRun     *github.WorkflowRun
+		lastSuccess *github.WorkflowRun
+	}
+)
+
+// getWorkflowLatestRun fetches the latest run of a workflow without timeframe (optionally filtered by status/conclusion),
+// returns nil if workflow doesn't have any matching run
+func (m *MetricsCollectorGithubWorkflows) getWorkflowLatestRun(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings, workflowID int64, status string) (*github.WorkflowRun, error) {
+	branch, filterBranches := workflowRunBranchFilter(repo, settings)
+
+	opts := github.ListWorkflowRunsOptions{
+		Branch:              branch,
+		Status:              status,
+		ExcludePullRequests: true,
+		ListOptions:         github.ListOptions{PerPage: 1, Page: 1},
+	}
+	if filterBranches {
+		// first run might not match the branches
+		opts.PerPage = 100
+	}
+
+	for {
+		m.Logger().Debug(`fetching latest workflow run`, slog.String("repository", repo.GetName()), slog.Int64("workflowID", workflowID), slog.String("status", status), slog.Int("page", opts.Page))
+
+		result, response, err := org.Client.Actions.ListWorkflowRunsByID(m.Context(), org.Name, repo.GetName(), workflowID, &opts)
+		var ghRateLimitError *github.RateLimitError
+		if ok := errors.As(err, &ghRateLimitError); ok {
+			m.Logger().Debug("request ListWorkflowRunsByID rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+			time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		// runs are sorted by creation time (newest first)
+		for _, workflowRun := range result.WorkflowRuns {
+			if filterBranches && !settings.MatchesBranch(workflowRun.GetHeadBranch()) {
+				continue
+			}
+			return workflowRun, nil
+		}
+
+		// calc next page
+		if !filterBranches || response.NextPage == 0 {
+			break
+		}
+		opts.Page = response.NextPage
+	}
+
+	return nil, nil
+}
+
+// getWorkflowLatestCompletedRun fetches the latest completed run of a workflow without timeframe,
+// only fetched once per workflow as the cached run is replaced by newer runs within the timeframe (see collectLatestRun)
+func (m *MetricsCollectorGithubWorkflows) getWorkflowLatestCompletedRun(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings, workflowID int64) (*github.WorkflowRun, error) {
+	cacheKey := workflowCacheKey(org, repo, settings, workflowID)
+	if workflowRun, exists := m.latestCompletedRuns[cacheKey]; exists {
+		return workflowRun, nil
+	}
+
+	workflowRun, err := m.getWorkflowLatestRun(org, repo, settings, workflowID, "completed")
+	if err != nil {
+		return nil, err
+	}
+
+	m.latestCompletedRuns[cacheKey] = workflowRun
+
+	return workflowRun, nil
+}
+
+// getWorkflowLatestPassingRun fetches the latest run of a workflow with a passing conclusion without timeframe,
+// returns nil if workflow doesn't have any passing run
+func (m *MetricsCollectorGithubWorkflows) getWorkflowLatestPassingRun(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings, workflowID int64) (*github.WorkflowRun, error) {
+	var ret *github.WorkflowRun
+	for _, conclusion := range settings.ConclusionsOfClass(config.ConclusionPassing) {
+		workflowRun, err := m.getWorkflowLatestRun(org, repo, settings, workflowID, conclusion)
+		if err != nil {
+			return nil, err
+		}
+
+		if workflowRun != nil && (ret == nil || ret.GetCreatedAt().Before(workflowRun.GetCreatedAt().Time)) {
+			ret = workflowRun
+		}
+	}
+	return ret, nil
+}
+
+// collectLastRun collects the last run and last successful (passing) run of every workflow, runs within the timeframe
+// are updating the cached runs, if there is no (successful) run cached the workflow runs are looked up without timeframe
+func (m *MetricsCollectorGithubWorkflows) collectLastRun(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings, workflows map[int64]*github.Workflow, workflowRuns []*github.WorkflowRun, callback chan<- func()) {
+	lastRunMetric := m.getMetricList("workflowLastRunTimestamp")
+	lastSuccessMetric := m.getMetricList("workflowLastSuccessTimestamp")
+	neverSucceededMetric := m.getMetricList("workflowNeverSucceeded")
+
+	lastRuns := map[int64]*githubWorkflowLastRun{}
+	for workflowID := range workflows {
+		lastRuns[workflowID] = &githubWorkflowLastRun{}
+	}
+
+	// runs within timeframe
+	for _, workflowRun := range workflowRuns {
+		lastRun, exists := lastRuns[workflowRun.GetWorkflowID()]
+		if !exists {
+			continue
+		}
+
+		if lastRun.lastRun == nil || lastRun.lastRun.GetCreatedAt().Before(workflowRun.GetCreatedAt().Time) {
+			lastRun.lastRun = workflowRun
+		}
+
+		if settings.ConclusionClass(workflowRun.GetConclusion()) == config.ConclusionPassing {
+			if lastRun.lastSuccess == nil || lastRun.lastSuccess.GetCreatedAt().Before(workflowRun.GetCreatedAt().Time) {
+				lastRun.lastSuccess = workflowRun
+			}
+		}
+	}
+
+	for workflowID, lastRun := range lastRuns {
+		workflow := workflows[workflowID]
+
+		cacheKey := workflowCacheKey(org, repo, settings, workflowID)
+		cachedLastRun, exists := m.lastRuns[cacheKey]
+		if !exists && (lastRun.lastRun == nil || lastRun.lastSuccess == nil) {
+			// lookup runs outside of timeframe
+			var err error
+			cachedLastRun = &githubWorkflowLastRun{}
+
+			cachedLastRun.lastRun, err = m.getWorkflowLatestRun(org, repo, settings, workflowID, "")
+			if err == nil && cachedLastRun.lastRun != nil {
+				cachedLastRun.lastSuccess, err = m.getWorkflowLatestPassingRun(org, repo, settings, workflowID)
+			}
+			if err != nil {
+				m.Logger().Warn(`unable to fetch latest workflow run`, slog.String("repository", repo.GetName()), slog.Int64("workflowID", workflowID), slog.Any("error", err))
+				continue
+			}
+		} else if !exists {
+			cachedLastRun = &githubWorkflowLastRun{}
+		}
+
+		// cached runs are updated by newer runs within timeframe (cache is still valid after runs left the timeframe)
+		if lastRun.lastRun != nil && (cachedLastRun.lastRun == nil || cachedLastRun.lastRun.GetCreatedAt().Before(lastRun.lastRun.GetCreatedAt().Time)) {
+			cachedLastRun.lastRun = lastRun.lastRun
+		}
+		if lastRun.lastSuccess != nil && (cachedLastRun.lastSuccess == nil || cachedLastRun.lastSuccess.GetCreatedAt().Before(lastRun.lastSuccess.GetCreatedAt().Time)) {
+			cachedLastRun.lastSuccess = lastRun.lastSuccess
+		}
+		m.lastRuns[cacheKey] = cachedLastRun
+
+		labels := prometheus.Labels{
+			"org":        org.Name,
+			"repo":       repo.GetName(),
+			"workflowID": fmt.Sprintf("%v", workflowID),
+			"workflow":   workflow.GetName(),
+		}
+
+		if cachedLastRun.lastRun != nil {
+			lastRunMetric.AddTime(labels, cachedLastRun.lastRun.GetCreatedAt().Time)
+		}
+
+		if cachedLastRun.lastSuccess != nil {
+			lastSuccessMetric.AddTime(labels, cachedLastRun.lastSuccess.GetUpdatedAt().Time)
+		}
+
+		neverSucceededMetric.AddBool(labels, cachedLastRun.lastSuccess == nil)
+	}
+}
+
+// workflowCacheKey returns the cache key of a workflow (including branch filter)
+func workflowCacheKey(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings, workflowID int64) string {
+	return fmt.Sprintf("%s/%s/%d/%s", org.Name, repo.GetName(), workflowID, strings.Join(settings.Branches, ","))
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/exp/slices"
+
+	"github.com/webdevops/github-workflow-exporter/config"
+)
+
+// collectStuckRuns collects the age of queued and in progress workflow runs (also runs created before the timeframe), the age relative
+// to the p95 duration of completed runs of the workflow and if the run is stuck (running longer than allowed)
+func (m *MetricsCollectorGithubWorkflows) collectStuckRuns(org *GithubOrganization, repo *github.Repository, settings *config.RepositorySettings, workflows map[int64]*github.Workflow, workflowRuns []*github.WorkflowRun, ownerLabels prometheus.Labels, callback chan<- func()) {
+	durationP95Metric := m.getMetricList("workflowDurationP95")
+	runAgeMetric := m.getMetricList("workflowRunRunningAge")
+	runDurationRatioMetric := m.getMetricList("workflowRunRunningDurationRatio")
+	runStuckMetric := m.getMetricList("workflowRunStuck")
+
+	// p95 duration of completed runs within timeframe
+	durations := map[int64][]float64{}
+	for _, workflowRun := range workflowRuns {
+		if workflowRun.GetStatus() != "completed" || settings.ConclusionClass(workflowRun.GetConclusion()) == config.ConclusionIgnored {
+			continue
+		}
+
+		duration := workflowRun.GetUpdatedAt().Sub(workflowRun.GetRunStartedAt().Time)
+		if duration > 0 {
+			durations[workflowRun.GetWorkflowID()] = append(durations[workflowRun.GetWorkflowID()], duration.Seconds())
+		}
+	}
+
+	durationP95 := map[int64]float64{}
+	for workflowID, values := range durations {
+		workflow, exists := workflows[workflowID]
+		if !exists || len(values) < GITHUB_WORKFLOW_DURATION_MIN_RUNS {
+			continue
+		}
+
+		// nearest-rank percentile
+		sort.Float64s(values)
+		durationP95[workflowID] = values[int(math.Ceil(0.95*float64(len(values))))-1]
+
+		durationP95Metric.Add(prometheus.Labels{
+			"org":        org.Name,
+			"repo":       repo.GetName(),
+			"workflowID": fmt.Sprintf("%v", workflowID),
+			"workflow":   workflow.GetName(),
+		}, durationP95[workflowID])
+	}
+
+	// queued and in progress runs within timeframe and created before timeframe
+	runningRuns := []*github.WorkflowRun{}
+	for _, workflowRun := range workflowRuns {
+		if githubWorkflowRunIsStuckCandidate(workflowRun) {
+			runningRuns = append(runningRuns, workflowRun)
+		}
+	}
+
+	branch, filterBranches := workflowRunBranchFilter(repo, settings)
+	stuckRuns, err := m.getRepoWorkflowRunsByStatus(org, repo, settings, branch, filterBranches, githubWorkflowStuckStatus, "<"+time.Now().Add(-settings.Timeframe).UTC().Format(time.RFC3339))
+	if err != nil {
+		m.Logger().Warn(`unable to fetch stuck workflow runs`, slog.String("repository", repo.GetName()), slog.Any("error", err))
+	}
+	runningRuns = append(runningRuns, stuckRuns...)
+
+	processedRuns := map[int64]bool{}
+	for _, workflowRun := range runningRuns {
+		if processedRuns[workflowRun.GetID()] {
+			continue
+		}
+		processedRuns[workflowRun.GetID()] = true
+
+		startedAt := workflowRun.GetRunStartedAt().Time
+		if startedAt.IsZero() {
+			startedAt = workflowRun.GetCreatedAt().Time
+		}
+		age := time.Since(startedAt)
+
+		infoLabels := prometheus.Labels{
+			"org":               org.Name,
+			"repo":              repo.GetName(),
+			"workflowID":        fmt.Sprintf("%v", workflowRun.GetWorkflowID()),
+			"workflowRunNumber": fmt.Sprintf("%v", workflowRun.GetRunNumber()),
+			"workflow":          LABEL_VALUE_UNKNOWN,
+			"workflowRunUrl":    workflowRun.GetHTMLURL(),
+			"branch":            workflowRun.GetHeadBranch(),
+			"status":            workflowRun.GetStatus(),
+		}
+		if workflow, ok := workflows[workflowRun.GetWorkflowID()]; ok {
+			infoLabels["workflow"] = workflow.GetName()
+		}
+
+		statLabels := prometheus.Labels{
+			"org":               org.Name,
+			"repo":              repo.GetName(),
+			"workflowID":        fmt.Sprintf("%v", workflowRun.GetWorkflowID()),
+			"workflowRunNumber": fmt.Sprintf("%v", workflowRun.GetRunNumber()),
+		}
+
+		stuck := Opts.GitHub.Workflows.Stuck.MaxDuration > 0 && age > Opts.GitHub.Workflows.Stuck.MaxDuration
+		if p95, exists := durationP95[workflowRun.GetWorkflowID()]; exists && p95 > 0 {
+			runDurationRatioMetric.Add(statLabels, age.Seconds()/p95)
+
+			if Opts.GitHub.Workflows.Stuck.DurationMultiple > 0 && age.Seconds() > Opts.GitHub.Workflows.Stuck.DurationMultiple*p95 {
+				stuck = true
+			}
+		}
+
+		stuckLabels := prometheus.Labels{}
+		for labelName, labelValue := range infoLabels {
+			stuckLabels[labelName] = labelValue
+		}
+		for labelName, labelValue := range ownerLabels {
+			stuckLabels[labelName] = labelValue
+		}
+
+		runAgeMetric.Add(infoLabels, age.Seconds())
+		runStuckMetric.AddBool(stuckLabels, stuck)
+	}
+}
+
+// githubWorkflowRunIsStuckCandidate returns true if workflow run is queued or in progress (runs waiting for a review
+// or approval are not stuck, they are reported by the pendingDeployments and awaitingApproval collectors)
+func githubWorkflowRunIsStuckCandidate(workflowRun *github.WorkflowRun) bool {
+	return slices.Contains(githubWorkflowStuckStatus, workflowRun.GetStatus()) && workflowRun.GetConclusion() == ""
+}
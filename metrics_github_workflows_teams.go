@@ -0,0 +1,211 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v61/github"
+	"golang.org/x/exp/slices"
+)
+
+const (
+	TEAM_SOURCE_TEAMS      = "teams"
+	TEAM_SOURCE_CODEOWNERS = "codeowners"
+)
+
+type (
+	// githubCodeownersTeams are the teams of the CODEOWNERS file of a repository (version is default branch and push time)
+	githubCodeownersTeams struct {
+		version string
+		teams   []string
+	}
+)
+
+var (
+	// CODEOWNERS locations in order of precedence (same as GitHub)
+	githubCodeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+)
+
+// getOrgTeamRepositories returns the owning teams (slugs) per repository name using the team repository assignments
+func (m *MetricsCollectorGithubWorkflows) getOrgTeamRepositories(org *GithubOrganization) (map[string][]string, error) {
+	var teams []*github.Team
+
+	opts := github.ListOptions{PerPage: 100, Page: 1}
+
+	for {
+		m.Logger().Debug(`fetching team list`, slog.String("org", org.Name), slog.Int("page", opts.Page))
+
+		result, response, err := org.Client.Teams.ListTeams(m.Context(), org.Name, &opts)
+		var ghRateLimitError *github.RateLimitError
+		if ok := errors.As(err, &ghRateLimitError); ok {
+			m.Logger().Debug("request ListTeams rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+			time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		teams = append(teams, result...)
+
+		// calc next page
+		if response.NextPage == 0 {
+			break
+		}
+		opts.Page = response.NextPage
+	}
+
+	teamRepositories := map[string][]string{}
+	for _, team := range teams {
+		opts := github.ListOptions{PerPage: 100, Page: 1}
+
+		for {
+			m.Logger().Debug(`fetching team repository list`, slog.String("org", org.Name), slog.String("team", team.GetSlug()), slog.Int("page", opts.Page))
+
+			result, response, err := org.Client.Teams.ListTeamReposBySlug(m.Context(), org.Name, team.GetSlug(), &opts)
+			var ghRateLimitError *github.RateLimitError
+			if ok := errors.As(err, &ghRateLimitError); ok {
+				m.Logger().Debug("request ListTeamReposBySlug rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+				time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+				continue
+			} else if err != nil {
+				return nil, err
+			}
+
+			for _, repo := range result {
+				teamRepositories[repo.GetName()] = append(teamRepositories[repo.GetName()], team.GetSlug())
+			}
+
+			// calc next page
+			if response.NextPage == 0 {
+				break
+			}
+			opts.Page = response.NextPage
+		}
+	}
+
+	return teamRepositories, nil
+}
+
+// getRepoCodeownersTeams returns the owning teams (slugs) of the repository using the CODEOWNERS file,
+// teams (and missing CODEOWNERS files) are cached until the repository is pushed or the default branch is changed
+func (m *MetricsCollectorGithubWorkflows) getRepoCodeownersTeams(org *GithubOrganization, repo *github.Repository) ([]string, error) {
+	cacheKey := fmt.Sprintf("%s/%s", org.Name, repo.GetName())
+	version := fmt.Sprintf("%s@%s", repo.GetDefaultBranch(), repo.GetPushedAt().UTC().Format(time.RFC3339))
+	if cachedTeams, exists := m.codeownersTeams[cacheKey]; exists && cachedTeams.version == version {
+		return cachedTeams.teams, nil
+	}
+
+	teams, err := m.fetchRepoCodeownersTeams(org, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	// without push time there is no way to detect changes
+	if repo.PushedAt != nil {
+		m.codeownersTeams[cacheKey] = &githubCodeownersTeams{version: version, teams: teams}
+	}
+
+	return teams, nil
+}
+
+// fetchRepoCodeownersTeams fetches the CODEOWNERS file (first existing location) and returns its teams
+func (m *MetricsCollectorGithubWorkflows) fetchRepoCodeownersTeams(org *GithubOrganization, repo *github.Repository) ([]string, error) {
+	for _, path := range githubCodeownersPaths {
+		var fileContent *github.RepositoryContent
+		var err error
+		for {
+			m.Logger().Debug(`fetching CODEOWNERS`, slog.String("repository", repo.GetName()), slog.String("path", path))
+
+			fileContent, _, _, err = org.Client.Repositories.GetContents(m.Context(), org.Name, repo.GetName(), path, nil)
+			var ghRateLimitError *github.RateLimitError
+			if ok := errors.As(err, &ghRateLimitError); ok {
+				m.Logger().Debug("request GetContents rate limited", slog.Time("waitingUntil", ghRateLimitError.Rate.Reset.Time))
+				time.Sleep(time.Until(ghRateLimitError.Rate.Reset.Time))
+				continue
+			}
+			break
+		}
+
+		var ghErrorResponse *github.ErrorResponse
+		if ok := errors.As(err, &ghErrorResponse); ok && ghErrorResponse.Response.StatusCode == http.StatusNotFound {
+			// not found, try next location
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if fileContent == nil {
+			continue
+		}
+
+		content, err := fileContent.GetContent()
+		if err != nil {
+			return nil, err
+		}
+
+		return parseCodeownersTeams(org.Name, content), nil
+	}
+
+	return nil, nil
+}
+
+// parseCodeownersTeams returns the teams of the last rule matching all files ("*"),
+// if there is no such rule all teams of the CODEOWNERS file are returned
+func parseCodeownersTeams(org, content string) []string {
+	var globalTeams []string
+	var allTeams []string
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		// remove comments
+		if pos := strings.Index(line, "#"); pos >= 0 {
+			line = strings.TrimSpace(line[:pos])
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		var teams []string
+		for _, owner := range fields[1:] {
+			// only teams (@org/team-slug) of this org
+			owner = strings.TrimPrefix(owner, "@")
+			if orgName, teamSlug, found := strings.Cut(owner, "/"); found && strings.EqualFold(orgName, org) {
+				teams = append(teams, strings.ToLower(teamSlug))
+			}
+		}
+
+		switch fields[0] {
+		case "*", "/*", "/", "/**", "**":
+			globalTeams = teams
+		}
+
+		for _, team := range teams {
+			if !slices.Contains(allTeams, team) {
+				allTeams = append(allTeams, team)
+			}
+		}
+	}
+
+	if globalTeams != nil {
+		return globalTeams
+	}
+
+	return allTeams
+}
+
+// teamLabelValue returns the label value for a list of teams (sorted, comma separated)
+func teamLabelValue(teams []string) string {
+	teams = slices.Clone(teams)
+	slices.Sort(teams)
+	teams = slices.Compact(teams)
+	return strings.Join(teams, ",")
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseCodeownersTeams(t *testing.T) {
+	testCases := []struct {
+		name     string
+		content  string
+		expected []string
+	}{
+		{
+			name:     "empty file",
+			content:  "",
+			expected: nil,
+		},
+		{
+			name: "teams of global rule",
+			content: `
+# global owners
+* @webdevops/Platform @webdevops/security # comment
+/docs/ @webdevops/docs
+`,
+			expected: []string{"platform", "security"},
+		},
+		{
+			name: "last global rule wins",
+			content: `
+* @webdevops/platform
+/** @webdevops/backend
+`,
+			expected: []string{"backend"},
+		},
+		{
+			name: "all teams without global rule",
+			content: `
+/docs/ @webdevops/docs @webdevops/platform
+/src/ @webdevops/platform @webdevops/backend
+`,
+			expected: []string{"docs", "platform", "backend"},
+		},
+		{
+			name: "users and teams of other orgs are ignored",
+			content: `
+* @user @other/platform user@example.com @WebDevOps/backend
+`,
+			expected: []string{"backend"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			teams := parseCodeownersTeams("webdevops", testCase.content)
+			if strings.Join(teams, ",") != strings.Join(testCase.expected, ",") {
+				t.Errorf("expected teams %v, got %v", testCase.expected, teams)
+			}
+		})
+	}
+}